@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"encoding/json"
+	"log"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Details carries event-specific context, stored as JSON
+type Details map[string]interface{}
+
+// Long user agents are truncated rather than dropped
+const maxUserAgentLength = 512
+
+// Log appends an event about userID (0 when the account is unknown) to the audit log. The actor
+// is the authenticated user of the request, if any. Failures are logged rather than returned:
+// the action being audited has already happened.
+func Log(c *gin.Context, eventType string, userID uint, details Details) {
+	event := models.AuditEvent{Type: eventType}
+	if userID != 0 {
+		event.UserID = &userID
+	}
+
+	if c != nil {
+		if actor, ok := c.Get("user_id"); ok {
+			if id, ok := actor.(uint); ok {
+				event.ActorID = &id
+			}
+		}
+		event.IPAddress = c.ClientIP()
+		event.UserAgent = c.Request.UserAgent()
+		if len(event.UserAgent) > maxUserAgentLength {
+			event.UserAgent = event.UserAgent[:maxUserAgentLength]
+		}
+	}
+
+	if len(details) > 0 {
+		encoded, err := json.Marshal(details)
+		if err == nil {
+			event.Details = string(encoded)
+		}
+	}
+
+	if err := config.DB.Create(&event).Error; err != nil {
+		log.Printf("❌ Failed to write audit event %s: %v", eventType, err)
+	}
+}
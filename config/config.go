@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"gitconnect-backend/models"
 	"gorm.io/driver/postgres"
@@ -13,6 +14,38 @@ import (
 
 var DB *gorm.DB
 
+// Models are the tables AutoMigrate keeps up to date
+var Models = []interface{}{
+	&models.User{},
+	&models.Profile{},
+	&models.Post{},
+	&models.Comment{},
+	&models.RefreshToken{},
+	&models.RevokedToken{},
+	&models.TokenCutoff{},
+	&models.PasswordResetToken{},
+	&models.EmailChangeToken{},
+	&models.RecoveryCode{},
+	&models.OAuthAccount{},
+	&models.PersonalAccessToken{},
+	&models.Session{},
+	&models.WebAuthnCredential{},
+	&models.WebAuthnChallenge{},
+	&models.MagicLinkToken{},
+	&models.AuditEvent{},
+	&models.PostReaction{},
+	&models.GithubProfile{},
+	&models.GithubRepository{},
+	&models.GithubPinnedRepository{},
+	&models.GithubLanguage{},
+	&models.GithubVerificationChallenge{},
+	&models.Skill{},
+	&models.SkillAlias{},
+	&models.ProfileSkill{},
+	&models.UserBlock{},
+	&models.PostMedia{},
+}
+
 // ConnectDatabase initializes and connects to the database.
 func ConnectDatabase() error {
 	// Load .env file for local development (Railway will inject env vars on deployment)
@@ -35,15 +68,49 @@ func ConnectDatabase() error {
 		return fmt.Errorf("❌ Failed to connect to database: %w", err)
 	}
 	
-	if err := database.AutoMigrate(&models.User{}, &models.Profile{}, &models.Post{}, &models.Comment{}); err != nil {
+	// Accounts that predate email verification are grandfathered in as verified
+	backfillVerified := database.Migrator().HasTable(&models.User{}) && !database.Migrator().HasColumn(&models.User{}, "EmailVerified")
+
+	if err := database.AutoMigrate(Models...); err != nil {
 		return fmt.Errorf("❌ Migration failed: %w", err)
 	}
+
+	if backfillVerified {
+		if err := database.Exec("UPDATE users SET email_verified = TRUE, email_verified_at = created_at").Error; err != nil {
+			return fmt.Errorf("❌ Migration failed: %w", err)
+		}
+	}
 	
 	DB = database
 	log.Println("✅ Database connected and migrated successfully")
 	return nil
 }
 
+// SeedAdmins promotes the users with the given comma-separated emails to admin,
+// so a fresh deployment has someone who can manage roles.
+func SeedAdmins(emails string) error {
+	var list []string
+	for _, email := range strings.Split(emails, ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			list = append(list, strings.ToLower(email))
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+
+	result := DB.Model(&models.User{}).
+		Where("LOWER(email) IN ? AND role <> ?", list, models.RoleAdmin).
+		Update("role", models.RoleAdmin)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("✅ Promoted %d user(s) to admin", result.RowsAffected)
+	}
+	return nil
+}
+
 // CloseDatabase gracefully closes the DB connection.
 func CloseDatabase() {
 	sqlDB, err := DB.DB()
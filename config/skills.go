@@ -0,0 +1,78 @@
+package config
+
+import (
+	"log"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm/clause"
+)
+
+// seedSkills are the canonical spellings of common technologies and the aliases folded into them.
+// Skills users add themselves are created as they spell them; admins can alias those later.
+var seedSkills = []struct {
+	Name    string
+	Aliases []string
+}{
+	{"Go", []string{"golang", "go-lang"}},
+	{"JavaScript", []string{"js", "ecmascript", "es6"}},
+	{"TypeScript", []string{"ts"}},
+	{"Python", []string{"py", "python3"}},
+	{"Java", nil},
+	{"Kotlin", nil},
+	{"C", nil},
+	{"C++", []string{"cplusplus", "c-plus-plus"}},
+	{"C#", []string{"c-sharp"}},
+	{".NET", []string{"net", "dot-net", "dotnet-core"}},
+	{"Rust", []string{"rustlang", "rust-lang"}},
+	{"Ruby", []string{"rb"}},
+	{"Ruby on Rails", []string{"rails", "ror"}},
+	{"PHP", nil},
+	{"Swift", nil},
+	{"Node.js", []string{"node", "nodejs"}},
+	{"React", []string{"reactjs", "react-js"}},
+	{"Vue.js", []string{"vue", "vuejs"}},
+	{"Angular", []string{"angularjs", "angular-js"}},
+	{"Django", nil},
+	{"Flask", nil},
+	{"Gin", []string{"gin-gonic"}},
+	{"GraphQL", []string{"gql"}},
+	{"PostgreSQL", []string{"postgres", "psql", "pgsql"}},
+	{"MySQL", nil},
+	{"MongoDB", []string{"mongo"}},
+	{"Redis", nil},
+	{"Docker", nil},
+	{"Kubernetes", []string{"k8s", "kube"}},
+	{"Terraform", nil},
+	{"AWS", []string{"amazon-web-services"}},
+	{"Google Cloud", []string{"gcp", "google-cloud-platform"}},
+	{"Azure", []string{"microsoft-azure"}},
+	{"Linux", nil},
+	{"Git", nil},
+}
+
+// SeedSkills makes sure the canonical skills and their aliases exist; it is safe to run on every start
+func SeedSkills() error {
+	created := 0
+	for _, seed := range seedSkills {
+		skill := models.Skill{Name: seed.Name, Slug: models.SkillSlug(seed.Name)}
+		result := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&skill)
+		if result.Error != nil {
+			return result.Error
+		}
+		created += int(result.RowsAffected)
+		if err := DB.Where("slug = ?", skill.Slug).First(&skill).Error; err != nil {
+			return err
+		}
+
+		for _, alias := range seed.Aliases {
+			record := models.SkillAlias{SkillID: skill.ID, Alias: models.SkillSlug(alias)}
+			if err := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error; err != nil {
+				return err
+			}
+		}
+	}
+	if created > 0 {
+		log.Printf("✅ Seeded %d skill(s)", created)
+	}
+	return nil
+}
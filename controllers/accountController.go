@@ -0,0 +1,338 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gitconnect-backend/audit"
+	"gitconnect-backend/config"
+	"gitconnect-backend/mailer"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const emailChangeLinkTTL = 24 * time.Hour
+
+var (
+	errEmailTaken              = errors.New("email already in use")
+	errEmailChangeTokenInvalid = errors.New("invalid email change token")
+)
+
+// ReauthRequest proves the caller is the account owner: the current password, or a 2FA code
+// (or recovery code) when 2FA is enabled
+type ReauthRequest struct {
+	CurrentPassword string `json:"current_password"`
+	Code            string `json:"code"`
+	RecoveryCode    string `json:"recovery_code"`
+}
+
+// ChangePasswordRequest sets a new password for the logged-in user
+type ChangePasswordRequest struct {
+	ReauthRequest
+	NewPassword string `json:"new_password" binding:"required,min=8,max=72"`
+}
+
+// ChangeEmailRequest starts moving the account to a new email address
+type ChangeEmailRequest struct {
+	ReauthRequest
+	NewEmail string `json:"new_email" binding:"required,email"`
+}
+
+// confirmIdentity checks the password or second factor in input; it writes the error response itself
+func confirmIdentity(c *gin.Context, user *models.User, input ReauthRequest) bool {
+	if input.CurrentPassword == "" && input.Code == "" && input.RecoveryCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Current password or two-factor code is required"})
+		return false
+	}
+	if !allowAttempt(c, twoFactorKey(user.ID), "") {
+		return false
+	}
+
+	if input.CurrentPassword != "" {
+		// Accounts created through GitHub have no password to compare against
+		if user.Password == "" || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.CurrentPassword)) != nil {
+			_, _ = AccountThrottle.Fail(twoFactorKey(user.ID))
+			audit.Log(c, models.AuditLoginFailed, user.ID, audit.Details{"reason": "reauthentication"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+			return false
+		}
+		return true
+	}
+
+	if err := checkSecondFactor(user, input.Code, input.RecoveryCode); err != nil {
+		if errors.Is(err, errSecondFactorInvalid) {
+			_, _ = AccountThrottle.Fail(twoFactorKey(user.ID))
+			audit.Log(c, models.AuditTwoFactorFailed, user.ID, nil)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify two-factor code"})
+		}
+		return false
+	}
+	return true
+}
+
+// revokeOtherSessions signs out every session of the user except the one making this request
+func revokeOtherSessions(c *gin.Context, userID uint) error {
+	var sessionIDs []uint
+	if err := config.DB.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL AND id <> ?", userID, currentSessionID(c)).
+		Pluck("id", &sessionIDs).Error; err != nil {
+		return err
+	}
+	for _, id := range sessionIDs {
+		if err := revokeSession(config.DB, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// invalidateEmailChanges voids every outstanding email change link of the user, so a link mailed
+// before a credential or address change cannot be redeemed after it
+func invalidateEmailChanges(tx *gorm.DB, userID uint) error {
+	return tx.Model(&models.EmailChangeToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
+
+// @Summary Change password
+// @Description Sets a new password after confirming the current password (or a 2FA code). Every other session is signed out, personal access tokens are revoked and pending email change links stop working.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param body body ChangePasswordRequest true "Current credential and new password"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/password [put]
+func ChangePassword(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	var input ChangePasswordRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !confirmIdentity(c, user, input.ReauthRequest) {
+		return
+	}
+	_ = AccountThrottle.Reset(twoFactorKey(user.ID))
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("password", string(hashedPassword)).Error; err != nil {
+			return err
+		}
+		return invalidateEmailChanges(tx, user.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	if err := revokeOtherSessions(c, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password changed but failed to sign out other sessions"})
+		return
+	}
+	if err := revokePersonalAccessTokens(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password changed but failed to revoke personal access tokens"})
+		return
+	}
+	audit.Log(c, models.AuditPasswordChanged, user.ID, nil)
+
+	if err := mailer.Default.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your GitConnect password was changed",
+		Body: fmt.Sprintf("Hi %s,\n\nThe password for your GitConnect account was just changed, your other devices were signed out and your personal access tokens were revoked.\n\n"+
+			"If this was not you, reset your password right away:\n\n%s\n",
+			user.Username, frontendURL("/forgot-password", nil)),
+	}); err != nil {
+		log.Println("❌ Failed to send password change notice:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed. Other sessions and personal access tokens have been revoked."})
+}
+
+// @Summary Change email address
+// @Description Confirms the current password (or a 2FA code) and mails a single-use confirmation link to the new address. The email only changes once that link is opened; asking again voids the earlier link.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param body body ChangeEmailRequest true "Current credential and new email"
+// @Security BearerAuth
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/email [put]
+func ChangeEmail(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	var input ChangeEmailRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	newEmail := strings.TrimSpace(input.NewEmail)
+	if strings.EqualFold(newEmail, user.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "That is already your email address"})
+		return
+	}
+	if !confirmIdentity(c, user, input.ReauthRequest) {
+		return
+	}
+	_ = AccountThrottle.Reset(twoFactorKey(user.ID))
+
+	var count int64
+	if err := config.DB.Model(&models.User{}).Where("LOWER(email) = LOWER(?)", newEmail).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change email"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already in use"})
+		return
+	}
+
+	raw, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change email"})
+		return
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Only the most recent link works
+		if err := invalidateEmailChanges(tx, user.ID); err != nil {
+			return err
+		}
+		return tx.Create(&models.EmailChangeToken{
+			UserID:    user.ID,
+			NewEmail:  newEmail,
+			TokenHash: utils.HashToken(raw),
+			ExpiresAt: time.Now().Add(emailChangeLinkTTL),
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change email"})
+		return
+	}
+	link := publicURL("/api/auth/email/confirm", url.Values{"token": {raw}})
+	if err := mailer.Default.Send(mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new GitConnect email address",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to make this the email address of your GitConnect account:\n\n%s\n\n"+
+			"The link expires in %d hours and can only be used once. If you did not ask for this, you can ignore this email.\n",
+			user.Username, link, int(emailChangeLinkTTL.Hours())),
+	}); err != nil {
+		log.Println("❌ Failed to send email change confirmation:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send confirmation email"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Check your new inbox to confirm the change"})
+}
+
+// @Summary Confirm email change
+// @Description Switches the account to the new address from the single-use link and notifies the old address
+// @Tags Account
+// @Produce json
+// @Param token query string true "Email change token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/email/confirm [get]
+func ConfirmEmailChange(c *gin.Context) {
+	var user models.User
+	var oldEmail, newEmail string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var token models.EmailChangeToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", utils.HashToken(c.Query("token"))).
+			First(&token).Error; err != nil {
+			return errEmailChangeTokenInvalid
+		}
+
+		now := time.Now()
+		if !token.IsActive(now) {
+			return errEmailChangeTokenInvalid
+		}
+		if err := tx.First(&user, token.UserID).Error; err != nil {
+			return err
+		}
+		if err := invalidateEmailChanges(tx, user.ID); err != nil {
+			return err
+		}
+		oldEmail, newEmail = user.Email, token.NewEmail
+		if strings.EqualFold(oldEmail, newEmail) {
+			return nil
+		}
+
+		var count int64
+		if err := tx.Model(&models.User{}).Where("LOWER(email) = LOWER(?)", newEmail).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errEmailTaken
+		}
+
+		// Opening the link proves control of the new mailbox
+		return tx.Model(&user).Updates(map[string]interface{}{
+			"email":             newEmail,
+			"email_verified":    true,
+			"email_verified_at": now,
+		}).Error
+	})
+	if errors.Is(err, errEmailChangeTokenInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired confirmation link"})
+		return
+	}
+	if errors.Is(err, errEmailTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already in use"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change email"})
+		return
+	}
+
+	if !strings.EqualFold(oldEmail, newEmail) {
+		middlewares.ForgetUser(user.ID)
+		audit.Log(c, models.AuditEmailChanged, user.ID, audit.Details{"from": oldEmail, "to": newEmail})
+		if err := mailer.Default.Send(mailer.Message{
+			To:      oldEmail,
+			Subject: "Your GitConnect email address was changed",
+			Body: fmt.Sprintf("Hi %s,\n\nThe email address of your GitConnect account was changed to %s.\n\n"+
+				"If this was not you, contact support immediately.\n",
+				user.Username, newEmail),
+		}); err != nil {
+			log.Println("❌ Failed to send email change notice:", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email address updated"})
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/mailer"
+	"gitconnect-backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+var emailChangeLinkToken = regexp.MustCompile(`email/confirm\?token=([A-Za-z0-9_-]+)`)
+
+func setupAccountTest(t *testing.T) (*gin.Engine, *mailer.MemoryMailer, *models.User) {
+	t.Helper()
+	setupTestDB(t)
+	outbox := mailer.NewMemoryMailer()
+	previous := mailer.Default
+	mailer.Default = outbox
+	t.Cleanup(func() { mailer.Default = previous })
+
+	user := createTestUser(t, "alice")
+	setPassword(t, user, "old-password")
+
+	router := gin.New()
+	account := router.Group("/api/auth", authenticateAs(user))
+	account.PUT("/password", ChangePassword)
+	account.PUT("/email", ChangeEmail)
+	router.GET("/api/auth/email/confirm", ConfirmEmailChange)
+	return router, outbox, user
+}
+
+// requestEmailChange asks to move the account to newEmail and returns the token mailed there
+func requestEmailChange(t *testing.T, router *gin.Engine, outbox *mailer.MemoryMailer, newEmail string) string {
+	t.Helper()
+	input := ChangeEmailRequest{ReauthRequest: ReauthRequest{CurrentPassword: "old-password"}, NewEmail: newEmail}
+	if status, body := doJSON(t, router, http.MethodPut, "/api/auth/email", input); status != http.StatusAccepted {
+		t.Fatalf("change email: status %d, %v", status, body)
+	}
+	message, ok := outbox.Last(newEmail)
+	if !ok {
+		t.Fatalf("no confirmation sent to %s", newEmail)
+	}
+	match := emailChangeLinkToken.FindStringSubmatch(message.Body)
+	if match == nil {
+		t.Fatalf("no confirmation link in %q", message.Body)
+	}
+	return match[1]
+}
+
+func confirmEmailChange(t *testing.T, router *gin.Engine, token string) int {
+	t.Helper()
+	status, _ := doJSON(t, router, http.MethodGet, "/api/auth/email/confirm?token="+token, nil)
+	return status
+}
+
+func TestConfirmEmailChangeLinkWorksOnce(t *testing.T) {
+	router, outbox, user := setupAccountTest(t)
+
+	first := requestEmailChange(t, router, outbox, "alice@new.example.com")
+	second := requestEmailChange(t, router, outbox, "alice@other.example.com")
+	if status := confirmEmailChange(t, router, first); status != http.StatusBadRequest {
+		t.Fatalf("superseded link: status %d, want 400", status)
+	}
+	if status := confirmEmailChange(t, router, second); status != http.StatusOK {
+		t.Fatalf("newest link: status %d, want 200", status)
+	}
+
+	// Switch back, then replay the link that moved the account away
+	config.DB.Model(user).Update("email", "alice@example.com")
+	if status := confirmEmailChange(t, router, second); status != http.StatusBadRequest {
+		t.Fatalf("replayed link: status %d, want 400", status)
+	}
+	var stored models.User
+	config.DB.First(&stored, user.ID)
+	if stored.Email != "alice@example.com" {
+		t.Fatalf("email is %q after replay", stored.Email)
+	}
+}
+
+func TestChangePasswordRevokesCredentials(t *testing.T) {
+	router, outbox, user := setupAccountTest(t)
+	config.DB.Create(&models.PersonalAccessToken{UserID: user.ID, Name: "CI", TokenHash: "hash", Scopes: models.ScopePostsWrite})
+	pending := requestEmailChange(t, router, outbox, "alice@new.example.com")
+
+	input := ChangePasswordRequest{ReauthRequest: ReauthRequest{CurrentPassword: "old-password"}, NewPassword: "new-password"}
+	if status, body := doJSON(t, router, http.MethodPut, "/api/auth/password", input); status != http.StatusOK {
+		t.Fatalf("change password: status %d, %v", status, body)
+	}
+
+	var activeTokens int64
+	config.DB.Model(&models.PersonalAccessToken{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Count(&activeTokens)
+	if activeTokens != 0 {
+		t.Fatalf("%d personal access tokens still active", activeTokens)
+	}
+	if status := confirmEmailChange(t, router, pending); status != http.StatusBadRequest {
+		t.Fatalf("link from before the password change: status %d, want 400", status)
+	}
+}
@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"gitconnect-backend/audit"
+	"gitconnect-backend/config"
+	"gitconnect-backend/mailer"
+	"gitconnect-backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Days a deletion request can still be cancelled, overridable with ACCOUNT_DELETION_GRACE_DAYS
+const defaultDeletionGraceDays = 14
+
+var errNotDue = errors.New("account deletion is not due")
+
+// accountDeletionGracePeriod returns how long a deletion request waits before the purge
+func accountDeletionGracePeriod() time.Duration {
+	days := defaultDeletionGraceDays
+	if value := os.Getenv("ACCOUNT_DELETION_GRACE_DAYS"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			days = parsed
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// deleteUserData removes a user and everything they authored: their profile, their posts with
+// every comment on them, and their comments on other posts. Their reactions to other posts are
+// taken back out of those posts' like and dislike counters. Reaction rows and auth records
+// (sessions, tokens, codes, linked accounts) go with the user through ON DELETE CASCADE. The
+// profile picture and post image blobs are outside the database, so callers delete them once the
+// transaction has committed.
+func deleteUserData(tx *gorm.DB, userID uint) error {
+	postIDs := tx.Model(&models.Post{}).Select("id").Where("user_id = ?", userID)
+
+	var reactions []struct {
+		PostID   uint
+		Likes    int
+		Dislikes int
+	}
+	if err := tx.Model(&models.PostReaction{}).
+		Select("post_id, SUM(CASE WHEN kind = ? THEN 1 ELSE 0 END) AS likes, SUM(CASE WHEN kind = ? THEN 1 ELSE 0 END) AS dislikes",
+			models.ReactionLike, models.ReactionDislike).
+		Where("user_id = ? AND post_id NOT IN (?)", userID, postIDs).
+		Group("post_id").
+		Scan(&reactions).Error; err != nil {
+		return err
+	}
+	for _, r := range reactions {
+		if err := tx.Model(&models.Post{}).Where("id = ?", r.PostID).Updates(map[string]interface{}{
+			"likes":    gorm.Expr("CASE WHEN likes > ? THEN likes - ? ELSE 0 END", r.Likes, r.Likes),
+			"dislikes": gorm.Expr("CASE WHEN dislikes > ? THEN dislikes - ? ELSE 0 END", r.Dislikes, r.Dislikes),
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Where("user_id = ? OR post_id IN (?)", userID, postIDs).Delete(&models.Comment{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&models.Post{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&models.Profile{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&models.User{}, userID).Error
+}
+
+// @Summary Request account deletion
+// @Description Confirms the current password (or a 2FA code) and schedules the account for deletion after a grace period. Other sessions and all personal access tokens are revoked; logging in again is still possible in order to cancel.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param body body ReauthRequest true "Current credential"
+// @Security BearerAuth
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/account/deletion [post]
+func RequestAccountDeletion(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	if user.DeletionPending() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Account deletion is already scheduled", "deletion_scheduled_at": user.DeletionScheduledAt})
+		return
+	}
+
+	var input ReauthRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !confirmIdentity(c, user, input) {
+		return
+	}
+	_ = AccountThrottle.Reset(twoFactorKey(user.ID))
+
+	scheduledAt := time.Now().Add(accountDeletionGracePeriod())
+	if err := config.DB.Model(user).Update("deletion_scheduled_at", scheduledAt).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule account deletion"})
+		return
+	}
+
+	audit.Log(c, models.AuditDeletionRequested, user.ID, audit.Details{"scheduled_at": scheduledAt})
+
+	// Only the device that asked stays signed in, so the request can be cancelled from it
+	if err := revokeOtherSessions(c, user.ID); err != nil {
+		log.Println("❌ Failed to sign out sessions of account pending deletion:", err)
+	}
+	if err := revokePersonalAccessTokens(user.ID); err != nil {
+		log.Println("❌ Failed to revoke tokens of account pending deletion:", err)
+	}
+
+	if err := mailer.Default.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your GitConnect account is scheduled for deletion",
+		Body: fmt.Sprintf("Hi %s,\n\nYour GitConnect account, profile, posts and comments will be permanently deleted on %s.\n\n"+
+			"Changed your mind? Log in before then and cancel the deletion from your account settings.\n",
+			user.Username, scheduledAt.UTC().Format("2 January 2006 15:04 MST")),
+	}); err != nil {
+		log.Println("❌ Failed to send account deletion notice:", err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":               "Account scheduled for deletion",
+		"deletion_scheduled_at": scheduledAt,
+	})
+}
+
+// @Summary Cancel account deletion
+// @Description Cancels a pending account deletion during its grace period
+// @Tags Account
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/account/deletion [delete]
+func CancelAccountDeletion(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	if !user.DeletionPending() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No account deletion is scheduled"})
+		return
+	}
+
+	if err := config.DB.Model(user).Update("deletion_scheduled_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel account deletion"})
+		return
+	}
+
+	audit.Log(c, models.AuditDeletionCancelled, user.ID, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Account deletion cancelled"})
+}
+
+// purgeAccount deletes one account whose grace period is over. The row is locked and re-checked
+// so a cancellation racing with the purge wins.
+func purgeAccount(userID uint, now time.Time) error {
+	var picture string
+	var media []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+		if !user.DeletionPending() || user.DeletionScheduledAt.After(now) {
+			return errNotDue
+		}
+		picture, media = profilePictureOf(tx, user.ID), userPostMediaKeys(tx, user.ID)
+		return deleteUserData(tx, user.ID)
+	})
+	if err != nil {
+		return err
+	}
+	deleteProfilePicture(context.Background(), picture)
+	deletePostMedia(context.Background(), media)
+	audit.Log(nil, models.AuditAccountPurged, userID, nil)
+	return revokeAllUserTokens(userID)
+}
+
+// PurgeDeletedAccounts deletes every account whose deletion grace period has passed
+func PurgeDeletedAccounts() (int, error) {
+	now := time.Now()
+	var userIDs []uint
+	if err := config.DB.Model(&models.User{}).
+		Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", now).
+		Pluck("id", &userIDs).Error; err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range userIDs {
+		err := purgeAccount(id, now)
+		if errors.Is(err, errNotDue) || errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// RunAccountPurger purges due account deletions now and then every interval; run it in a goroutine
+func RunAccountPurger(interval time.Duration) {
+	for {
+		purged, err := PurgeDeletedAccounts()
+		if err != nil {
+			log.Println("❌ Account purge failed:", err)
+		} else if purged > 0 {
+			log.Printf("🗑️ Purged %d deleted account(s)", purged)
+		}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/models"
+)
+
+func TestPurgeAccountTakesBackReactions(t *testing.T) {
+	setupTestDB(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+
+	liked := models.Post{Content: "liked by alice", UserID: bob.ID, Likes: 2}
+	disliked := models.Post{Content: "disliked by alice", UserID: bob.ID, Dislikes: 1}
+	own := models.Post{Content: "alice's own", UserID: alice.ID, Likes: 1}
+	for _, post := range []*models.Post{&liked, &disliked, &own} {
+		if err := config.DB.Create(post).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	config.DB.Create(&models.PostReaction{PostID: liked.ID, UserID: alice.ID, Kind: models.ReactionLike})
+	config.DB.Create(&models.PostReaction{PostID: liked.ID, UserID: bob.ID, Kind: models.ReactionLike})
+	config.DB.Create(&models.PostReaction{PostID: disliked.ID, UserID: alice.ID, Kind: models.ReactionDislike})
+	config.DB.Create(&models.PostReaction{PostID: own.ID, UserID: alice.ID, Kind: models.ReactionLike})
+
+	config.DB.Model(alice).Update("deletion_scheduled_at", time.Now().Add(-time.Minute))
+	if err := purgeAccount(alice.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	config.DB.First(&liked, liked.ID)
+	config.DB.First(&disliked, disliked.ID)
+	if liked.Likes != 1 || disliked.Dislikes != 0 {
+		t.Fatalf("counters after purge: %d likes, %d dislikes; want 1 and 0", liked.Likes, disliked.Dislikes)
+	}
+	var remaining int64
+	config.DB.Model(&models.PostReaction{}).Where("user_id = ?", alice.ID).Count(&remaining)
+	if remaining != 0 {
+		t.Fatalf("%d reactions of the purged user kept", remaining)
+	}
+	if err := config.DB.First(&models.Post{}, own.ID).Error; err == nil {
+		t.Fatal("purged user's post kept")
+	}
+}
@@ -0,0 +1,377 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitconnect-backend/audit"
+	"gitconnect-backend/config"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// UpdateRoleRequest changes a user's role
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user moderator admin"`
+}
+
+// pagination reads ?page= and ?limit= with sane bounds
+func pagination(c *gin.Context) (page, limit int) {
+	page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageSize)))
+	if limit < 1 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	return page, limit
+}
+
+// adminTargetUser loads the user named by the :id path parameter; it writes the error response itself
+func adminTargetUser(c *gin.Context) (*models.User, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, false
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// isSelf stops admins from demoting, suspending or deleting themselves and losing access
+func isSelf(c *gin.Context, user *models.User) bool {
+	if id, ok := middlewares.CurrentUserID(c); ok && id == user.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot perform this action on your own account"})
+		return true
+	}
+	return false
+}
+
+// @Summary List users
+// @Description Lists users for moderation, optionally filtered by a username/email search and role
+// @Tags Admin
+// @Produce json
+// @Param q query string false "Search username or email"
+// @Param role query string false "Filter by role"
+// @Param page query int false "Page number"
+// @Param limit query int false "Page size"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/users [get]
+func AdminListUsers(c *gin.Context) {
+	page, limit := pagination(c)
+
+	query := config.DB.Model(&models.User{})
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		like := "%" + strings.ToLower(q) + "%"
+		query = query.Where("LOWER(username) LIKE ? OR LOWER(email) LIKE ?", like, like)
+	}
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	var users []models.User
+	if err := query.Order("id").Offset((page - 1) * limit).Limit(limit).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users, "page": page, "limit": limit, "total": total})
+}
+
+// @Summary Get a user
+// @Description Fetch a user with their profile
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/admin/users/{id} [get]
+func AdminGetUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Preload("Profile").First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+// @Summary Change a user's role
+// @Description Sets the role (user, moderator, admin). The user's existing tokens are revoked so the change applies immediately.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param body body UpdateRoleRequest true "New role"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/users/{id}/role [put]
+func AdminUpdateRole(c *gin.Context) {
+	user, ok := adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	var input UpdateRoleRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if isSelf(c, user) {
+		return
+	}
+
+	previousRole := user.Role
+	if err := config.DB.Model(user).Update("role", input.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+	// Tokens carry the role, so old ones must not keep the old privileges
+	if err := revokeAllUserTokens(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Role updated but failed to revoke existing sessions"})
+		return
+	}
+	audit.Log(c, models.AuditRoleChanged, user.ID, audit.Details{"from": previousRole, "to": input.Role})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated", "user": user})
+}
+
+// @Summary Suspend a user
+// @Description Blocks the account from logging in and revokes all of its tokens
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/users/{id}/suspend [post]
+func AdminSuspendUser(c *gin.Context) {
+	user, ok := adminTargetUser(c)
+	if !ok || isSelf(c, user) {
+		return
+	}
+
+	if err := config.DB.Model(user).Update("suspended_at", time.Now()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suspend user"})
+		return
+	}
+	if err := revokeAllUserTokens(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User suspended but failed to revoke existing sessions"})
+		return
+	}
+	audit.Log(c, models.AuditUserSuspended, user.ID, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "User suspended"})
+}
+
+// @Summary Unsuspend a user
+// @Description Lets a suspended account log in again
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/users/{id}/unsuspend [post]
+func AdminUnsuspendUser(c *gin.Context) {
+	user, ok := adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Model(user).Update("suspended_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsuspend user"})
+		return
+	}
+	middlewares.ForgetUser(user.ID)
+	audit.Log(c, models.AuditUserUnsuspended, user.ID, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unsuspended"})
+}
+
+// @Summary Unlock a user's login
+// @Description Clears a lockout caused by too many failed login attempts
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/users/{id}/unlock [post]
+func AdminUnlockUser(c *gin.Context) {
+	user, ok := adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	if err := AccountThrottle.Reset(loginAccountKey(user.Email)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock user"})
+		return
+	}
+	audit.Log(c, models.AuditAccountUnlocked, user.ID, audit.Details{"via": "admin"})
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked"})
+}
+
+// @Summary Delete a user
+// @Description Permanently deletes a user along with their profile, posts and comments
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/users/{id} [delete]
+func AdminDeleteUser(c *gin.Context) {
+	user, ok := adminTargetUser(c)
+	if !ok || isSelf(c, user) {
+		return
+	}
+
+	var picture string
+	var media []string
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Locked as a purge does, so the blob keys read here belong to the rows being deleted
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.User{}, user.ID).Error; err != nil {
+			return err
+		}
+		picture, media = profilePictureOf(tx, user.ID), userPostMediaKeys(tx, user.ID)
+		return deleteUserData(tx, user.ID)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+	deleteProfilePicture(c.Request.Context(), picture)
+	deletePostMedia(c.Request.Context(), media)
+	_ = revokeAllUserTokens(user.ID)
+	audit.Log(c, models.AuditUserDeleted, user.ID, audit.Details{"user_id": user.ID, "username": user.Username})
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted"})
+}
+
+// @Summary Remove a post
+// @Description Deletes any post with its comments and images (moderators and admins)
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Post ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/posts/{id} [delete]
+func AdminDeletePost(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	var post models.Post
+	if err := config.DB.First(&post, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
+	media := postMediaKeys(config.DB, []uint{post.ID})
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("post_id = ?", post.ID).Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&post).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete post"})
+		return
+	}
+	deletePostMedia(c.Request.Context(), media)
+	audit.Log(c, models.AuditPostRemoved, post.UserID, audit.Details{"post_id": post.ID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post removed"})
+}
+
+// @Summary Remove a comment
+// @Description Deletes any comment (moderators and admins)
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/comments/{id} [delete]
+func AdminDeleteComment(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	var comment models.Comment
+	if err := config.DB.First(&comment, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	if err := config.DB.Delete(&comment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+	audit.Log(c, models.AuditCommentRemoved, comment.UserID, audit.Details{"comment_id": comment.ID, "post_id": comment.PostID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment removed"})
+}
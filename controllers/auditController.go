@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// auditEventJSON returns an audit event with its details as a JSON object rather than a string
+func auditEventJSON(event models.AuditEvent) gin.H {
+	entry := gin.H{
+		"id":         event.ID,
+		"type":       event.Type,
+		"user_id":    event.UserID,
+		"ip_address": event.IPAddress,
+		"user_agent": event.UserAgent,
+		"created_at": event.CreatedAt,
+	}
+	if event.ActorID != nil {
+		entry["actor_id"] = event.ActorID
+	}
+	if event.Details != "" {
+		entry["details"] = json.RawMessage(event.Details)
+	}
+	return entry
+}
+
+// parseTimeQuery reads an optional RFC 3339 timestamp from the query string
+func parseTimeQuery(c *gin.Context, name string) (*time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name + " time, expected RFC 3339"})
+		return nil, false
+	}
+	return &parsed, true
+}
+
+// @Summary List audit events
+// @Description Searches the security audit log, newest first, by user, event type and time range
+// @Tags Admin
+// @Produce json
+// @Param user_id query int false "Events about this user"
+// @Param type query string false "Comma-separated event types"
+// @Param from query string false "Earliest time (RFC 3339)"
+// @Param to query string false "Latest time (RFC 3339)"
+// @Param page query int false "Page number"
+// @Param limit query int false "Page size"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/audit [get]
+func AdminListAuditEvents(c *gin.Context) {
+	page, limit := pagination(c)
+
+	query := config.DB.Model(&models.AuditEvent{})
+	if value := c.Query("user_id"); value != "" {
+		userID, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		query = query.Where("user_id = ?", userID)
+	}
+	if value := c.Query("type"); value != "" {
+		var types []string
+		for _, t := range strings.Split(value, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				types = append(types, t)
+			}
+		}
+		if len(types) > 0 {
+			query = query.Where("type IN ?", types)
+		}
+	}
+	from, ok := parseTimeQuery(c, "from")
+	if !ok {
+		return
+	}
+	to, ok := parseTimeQuery(c, "to")
+	if !ok {
+		return
+	}
+	if from != nil {
+		query = query.Where("created_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("created_at <= ?", *to)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit events"})
+		return
+	}
+
+	var events []models.AuditEvent
+	if err := query.Order("created_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit events"})
+		return
+	}
+
+	list := make([]gin.H, len(events))
+	for i, event := range events {
+		list[i] = auditEventJSON(event)
+	}
+	c.JSON(http.StatusOK, gin.H{"events": list, "page": page, "limit": limit, "total": total})
+}
+
+// @Summary Recent security activity
+// @Description Lists the security events on the current user's account, newest first: logins, failed attempts, credential changes and sign-outs
+// @Tags Account
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Page size"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/activity [get]
+func MySecurityActivity(c *gin.Context) {
+	userID, exists := middlewares.CurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	page, limit := pagination(c)
+
+	var events []models.AuditEvent
+	if err := config.DB.Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).
+		Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch security activity"})
+		return
+	}
+
+	// Which admin acted on the account is not shown to the user
+	list := make([]gin.H, len(events))
+	for i, event := range events {
+		entry := auditEventJSON(event)
+		delete(entry, "actor_id")
+		delete(entry, "user_id")
+		list[i] = entry
+	}
+	c.JSON(http.StatusOK, gin.H{"events": list, "page": page, "limit": limit})
+}
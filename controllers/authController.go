@@ -1,27 +1,42 @@
 package controllers
 
 import (
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"gitconnect-backend/audit"
 	"gitconnect-backend/config"
 	"gitconnect-backend/models"
-	"gitconnect-backend/utils"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
+// RegisterRequest creates an account; everything else about the user is set by the server
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=30,username"`
+	Email    string `json:"email" binding:"required,email,max=254"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+// LoginRequest is a password login
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,max=72"`
+}
+
 // @Summary Register a new user
-// @Description Creates a new user account with a hashed password
+// @Description Creates a new, unverified user account with a hashed password and emails a verification link
 // @Tags Auth
 // @Accept json
 // @Produce json
-// @Param user body models.User true "User Data"
+// @Param user body RegisterRequest true "User Data"
 // @Success 201 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/auth/register [post]
 func Register(c *gin.Context) {
-	var input models.User
+	var input RegisterRequest
 
 	// Bind JSON input
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -43,29 +58,26 @@ func Register(c *gin.Context) {
 		Password: string(hashedPassword),
 	}
 
-	// Save user to DB
-	if err := config.DB.Create(&user).Error; err != nil {
+	// Save user to DB together with the profile linked to it
+	var profile models.Profile
+	if err := createAccount(config.DB, &user, &profile); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
 
-	// Automatically create a profile linked to the new user
-	profile := models.Profile{
-		UserID: user.ID,
-	}
-
-	if err := config.DB.Create(&profile).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create profile"})
-		return
+	// Send the verification link; the account exists either way and the user can ask for a resend
+	if err := sendVerificationEmail(&user); err != nil {
+		log.Println("❌ Failed to send verification email:", err)
 	}
 
 	// Return user and profile info
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "User registered successfully",
+		"message": "User registered successfully. Check your email to verify your address.",
 		"user": gin.H{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
+			"id":             user.ID,
+			"username":       user.Username,
+			"email":          user.Email,
+			"email_verified": user.EmailVerified,
 		},
 		"profile": gin.H{
 			"id":     profile.ID,
@@ -75,19 +87,35 @@ func Register(c *gin.Context) {
 }
 
 
+// createAccount saves a new user and automatically creates the profile linked to it, atomically
+func createAccount(db *gorm.DB, user *models.User, profile *models.Profile) error {
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		profile.UserID = user.ID
+		return tx.Create(profile).Error
+	})
+}
+
 // @Summary Login user
-// @Description Authenticates a user and returns a JWT token
+// @Description Authenticates a user and returns a short-lived JWT access token plus a refresh token. Accounts with 2FA get a challenge token to complete at /api/auth/2fa/verify instead.
 // @Tags Auth
 // @Accept json
 // @Produce json
-// @Param credentials body models.User true "User Credentials"
+// @Param credentials body LoginRequest true "User Credentials"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 423 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
 // @Failure 500 {object} map[string]string
 // @Router /api/auth/login [post]
 func Login(c *gin.Context) {
-	var input models.User
+	var input LoginRequest
 	var user models.User
 
 	// Bind JSON input
@@ -96,9 +124,15 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// Refuse throttled or locked-out attempts before spending any time on bcrypt
+	if !allowAttempt(c, loginAccountKey(input.Email), loginIPKey(c)) {
+		return
+	}
+
 	// Check if user exists
 	result := config.DB.Where("email = ?", input.Email).First(&user)
 	if result.Error != nil {
+		recordFailedLogin(c, input.Email, nil)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
@@ -106,25 +140,48 @@ func Login(c *gin.Context) {
 	// Compare password
 	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password))
 	if err != nil {
+		recordFailedLogin(c, input.Email, &user)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
+	_ = AccountThrottle.Reset(loginAccountKey(input.Email))
+
+	if user.IsSuspended() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This account has been suspended"})
+		return
+	}
+
+	// Password alone is not enough once 2FA is on: hand out a challenge instead of tokens
+	if user.TOTPEnabled {
+		startTwoFactorChallenge(c, &user)
+		return
+	}
+
+	completeLogin(c, &user, "password", "Login successful")
+}
 
-	// Generate token
-	token, err := utils.GenerateToken(user.ID)
+// completeLogin issues access + refresh tokens for an authenticated user and writes the response
+func completeLogin(c *gin.Context, user *models.User, method, message string) {
+	if user.IsSuspended() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This account has been suspended"})
+		return
+	}
+
+	tokens, err := issueTokenPair(config.DB, c, user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
-
-	// ✅ Return token + user ID + username
-	c.JSON(http.StatusOK, gin.H{
-		"message":  "Login successful",
-		"token":    token,
-		"user": gin.H{
-			"id":       user.ID,
-			"username": user.Username,
-		},
-	})
+	audit.Log(c, models.AuditLoginSucceeded, user.ID, audit.Details{"method": method})
+
+	// ✅ Return tokens + user ID + username
+	response := tokens.JSON()
+	response["message"] = message
+	response["user"] = gin.H{
+		"id":       user.ID,
+		"username": user.Username,
+		"role":     user.Role,
+	}
+	c.JSON(http.StatusOK, response)
 }
 
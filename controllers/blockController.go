@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BlockView is one user the caller has blocked
+type BlockView struct {
+	User      Author    `json:"user"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+// hiddenUserIDs returns the users whose content userID must not see: those they blocked and those
+// who blocked them. Anonymous callers (userID 0) see everything.
+func hiddenUserIDs(userID uint) ([]uint, error) {
+	if userID == 0 {
+		return nil, nil
+	}
+	var blocked, blockers []uint
+	if err := config.DB.Model(&models.UserBlock{}).Where("blocker_id = ?", userID).Pluck("blocked_id", &blocked).Error; err != nil {
+		return nil, err
+	}
+	if err := config.DB.Model(&models.UserBlock{}).Where("blocked_id = ?", userID).Pluck("blocker_id", &blockers).Error; err != nil {
+		return nil, err
+	}
+	return append(blocked, blockers...), nil
+}
+
+// blockedBetween reports whether either user has blocked the other
+func blockedBetween(db *gorm.DB, a, b uint) (bool, error) {
+	if a == 0 || b == 0 || a == b {
+		return false, nil
+	}
+	var count int64
+	err := db.Model(&models.UserBlock{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", a, b, b, a).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// blockTarget parses the :userId path parameter; it writes the error response itself
+func blockTarget(c *gin.Context) (uint, uint, bool) {
+	userID, exists := middlewares.CurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return 0, 0, false
+	}
+	target, err := strconv.ParseUint(c.Param("userId"), 10, 0)
+	if err != nil || target == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, 0, false
+	}
+	if uint(target) == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot block yourself"})
+		return 0, 0, false
+	}
+	return userID, uint(target), true
+}
+
+// @Summary List blocked users
+// @Description Lists the users the caller has blocked, most recent first
+// @Tags Blocks
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/blocks [get]
+func ListBlocks(c *gin.Context) {
+	userID, exists := middlewares.CurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var blocks []models.UserBlock
+	if err := config.DB.Preload("Blocked").Where("blocker_id = ?", userID).
+		Order("created_at DESC").Find(&blocks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch blocked users"})
+		return
+	}
+
+	userIDs := make([]uint, len(blocks))
+	for i, block := range blocks {
+		userIDs[i] = block.BlockedID
+	}
+	profiles := authorProfiles(userIDs)
+
+	list := make([]BlockView, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Blocked == nil {
+			continue
+		}
+		list = append(list, BlockView{User: authorOf(block.Blocked, profiles), BlockedAt: block.CreatedAt})
+	}
+	c.JSON(http.StatusOK, gin.H{"blocks": list})
+}
+
+// @Summary Block a user
+// @Description Blocks a user. Neither of you sees the other's posts or comments, nor can comment on or react to them. Blocking someone already blocked is a no-op.
+// @Tags Blocks
+// @Produce json
+// @Param userId path int true "User ID"
+// @Security BearerAuth
+// @Success 201 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/blocks/{userId} [post]
+func BlockUser(c *gin.Context) {
+	userID, target, ok := blockTarget(c)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Select("id").First(&user, target).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	block := models.UserBlock{BlockerID: userID, BlockedID: user.ID}
+	if err := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&block).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to block user"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "User blocked"})
+}
+
+// @Summary Unblock a user
+// @Description Removes a block the caller made
+// @Tags Blocks
+// @Produce json
+// @Param userId path int true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/blocks/{userId} [delete]
+func UnblockUser(c *gin.Context) {
+	userID, target, ok := blockTarget(c)
+	if !ok {
+		return
+	}
+
+	result := config.DB.Where("blocker_id = ? AND blocked_id = ?", userID, target).Delete(&models.UserBlock{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unblock user"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not blocked"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "User unblocked"})
+}
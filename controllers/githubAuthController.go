@@ -0,0 +1,259 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/github"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	providerGitHub    = "github"
+	githubStateCookie = "gh_oauth_state"
+	githubStateTTL    = 10 * time.Minute
+)
+
+var errNoVerifiedEmail = errors.New("GitHub account has no verified email")
+
+func githubRedirectURL() string {
+	if github.Default.RedirectURL != "" {
+		return github.Default.RedirectURL
+	}
+	return publicURL("/api/auth/github/callback", nil)
+}
+
+// @Summary Sign in with GitHub
+// @Description Redirects to GitHub to authorize GitConnect. GitHub sends the user back to /api/auth/github/callback.
+// @Tags Auth
+// @Success 302
+// @Failure 503 {object} map[string]string
+// @Router /api/auth/github [get]
+func GitHubLogin(c *gin.Context) {
+	if !github.Default.OAuthConfigured() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "GitHub sign-in is not configured"})
+		return
+	}
+
+	state, err := utils.GenerateOpaqueToken(24)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start GitHub sign-in"})
+		return
+	}
+
+	// The callback must present the same state it finds in this cookie (CSRF protection)
+	secure := os.Getenv("GIN_MODE") == "release"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(githubStateCookie, state, int(githubStateTTL.Seconds()), "/api/auth/github", "", secure, true)
+
+	c.Redirect(http.StatusFound, github.Default.AuthorizeURL(state, githubRedirectURL()))
+}
+
+// @Summary GitHub sign-in callback
+// @Description Completes the GitHub authorization-code flow. Links the GitHub identity to an existing account by verified email, or creates a new account and profile. Linking an account whose email was never verified drops its password, 2FA and passkeys and signs out its sessions and tokens, since whoever registered it had not proven they own the address.
+// @Tags Auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from /api/auth/github"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/github/callback [get]
+func GitHubCallback(c *gin.Context) {
+	if errParam := c.Query("error"); errParam != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "GitHub sign-in was cancelled: " + errParam})
+		return
+	}
+
+	expected, err := c.Cookie(githubStateCookie)
+	state := c.Query("state")
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(state)) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid OAuth state"})
+		return
+	}
+	c.SetCookie(githubStateCookie, "", -1, "/api/auth/github", "", os.Getenv("GIN_MODE") == "release", true)
+
+	code := c.Query("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing authorization code"})
+		return
+	}
+
+	accessToken, err := github.Default.ExchangeCode(code, githubRedirectURL())
+	if err != nil {
+		log.Println("❌ GitHub code exchange failed:", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to sign in with GitHub"})
+		return
+	}
+	ghUser, err := github.Default.GetAuthenticatedUser(accessToken)
+	if err != nil {
+		log.Println("❌ Failed to fetch GitHub user:", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to sign in with GitHub"})
+		return
+	}
+	emails, err := github.Default.GetVerifiedEmails(accessToken)
+	if err != nil {
+		log.Println("❌ Failed to fetch GitHub emails:", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to sign in with GitHub"})
+		return
+	}
+
+	user, created, err := resolveGitHubUser(ghUser, emails)
+	if errors.Is(err, errNoVerifiedEmail) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Your GitHub account has no verified email address"})
+		return
+	}
+	if err != nil {
+		log.Println("❌ Failed to link GitHub account:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in with GitHub"})
+		return
+	}
+
+	if user.TOTPEnabled {
+		startTwoFactorChallenge(c, user)
+		return
+	}
+
+	message := "Login successful"
+	if created {
+		message = "Account created with GitHub"
+	}
+	completeLogin(c, user, "github", message)
+}
+
+// resolveGitHubUser finds the account for a GitHub identity, linking or creating one as needed.
+// It reports whether a new account was created.
+func resolveGitHubUser(ghUser *github.User, verifiedEmails []string) (*models.User, bool, error) {
+	var user models.User
+	created, claimed := false, false
+	subject := strconv.FormatInt(ghUser.ID, 10)
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var account models.OAuthAccount
+		err := tx.Where("provider = ? AND provider_user_id = ?", providerGitHub, subject).First(&account).Error
+		switch {
+		case err == nil:
+			if err := tx.First(&user, account.UserID).Error; err != nil {
+				return err
+			}
+			if account.Login != ghUser.Login {
+				if err := tx.Model(&account).Update("login", ghUser.Login).Error; err != nil {
+					return err
+				}
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if len(verifiedEmails) == 0 {
+				return errNoVerifiedEmail
+			}
+
+			// Only link on an address GitHub has verified, otherwise anyone could claim an account
+			err := tx.Where("LOWER(email) IN ?", lowerAll(verifiedEmails)).First(&user).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				if err := createGitHubAccount(tx, &user, ghUser, verifiedEmails[0]); err != nil {
+					return err
+				}
+				created = true
+			} else if err != nil {
+				return err
+			} else if !user.EmailVerified {
+				// GitHub vouches for this address, which the account never proved it owns
+				if err := claimUnverifiedAccount(tx, &user); err != nil {
+					return err
+				}
+				claimed = true
+			}
+
+			account = models.OAuthAccount{
+				UserID:         user.ID,
+				Provider:       providerGitHub,
+				ProviderUserID: subject,
+				Login:          ghUser.Login,
+			}
+			if err := tx.Create(&account).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		// Fill in the GitHub handle on the profile if the user has not set one
+		return tx.Model(&models.Profile{}).
+			Where("user_id = ? AND (github IS NULL OR github = '')", user.ID).
+			Update("github", ghUser.Login).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	middlewares.ForgetUser(user.ID)
+	if claimed {
+		if err := revokeClaimedAccount(nil, &user, providerGitHub); err != nil {
+			return nil, false, err
+		}
+	}
+	return &user, created, nil
+}
+
+// createGitHubAccount creates a User+Profile for a first-time GitHub sign-in, as Register does.
+// The account has no password; the user can set one through the password reset flow.
+func createGitHubAccount(tx *gorm.DB, user *models.User, ghUser *github.User, email string) error {
+	username, err := availableUsername(tx, ghUser.Login)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	*user = models.User{
+		Username:        username,
+		Email:           email,
+		EmailVerified:   true,
+		EmailVerifiedAt: &now,
+	}
+	profile := models.Profile{
+		FullName: ghUser.Name,
+		Bio:      ghUser.Bio,
+		Github:   ghUser.Login,
+	}
+	return createAccount(tx, user, &profile)
+}
+
+// availableUsername returns base, or base with a numeric suffix if it is taken
+func availableUsername(tx *gorm.DB, base string) (string, error) {
+	candidate := base
+	for i := 2; i < 100; i++ {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	suffix, err := utils.GenerateOpaqueToken(4)
+	if err != nil {
+		return "", err
+	}
+	return base + "-" + suffix, nil
+}
+
+func lowerAll(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, v := range values {
+		lowered[i] = strings.ToLower(v)
+	}
+	return lowered
+}
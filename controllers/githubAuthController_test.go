@@ -0,0 +1,303 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/github"
+	"gitconnect-backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+func githubAuthRouter() *gin.Engine {
+	router := gin.New()
+	router.GET("/api/auth/github", GitHubLogin)
+	router.GET("/api/auth/github/callback", GitHubCallback)
+	return router
+}
+
+// githubTestAccount registers a GitHub user with the given emails, the first primary
+func githubTestAccount(stub *fakeGitHub, id int64, login string, emails ...github.Email) {
+	account := stub.addAccount(id, login)
+	account.Bio = "Building things"
+	if len(emails) > 0 {
+		emails[0].Primary = true
+	}
+	account.Emails = emails
+}
+
+func verified(email string) github.Email { return github.Email{Email: email, Verified: true} }
+
+func unverified(email string) github.Email { return github.Email{Email: email} }
+
+// beginGitHubLogin starts the flow and returns the state cookie the callback needs
+func beginGitHubLogin(t *testing.T, stub *fakeGitHub, router *gin.Engine) *http.Cookie {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/github", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("GitHubLogin: status %d, %s", w.Code, w.Body.String())
+	}
+
+	var state *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == githubStateCookie {
+			state = cookie
+		}
+	}
+	location, _ := url.Parse(w.Header().Get("Location"))
+	if state == nil || !state.HttpOnly || location.Query().Get("state") != state.Value {
+		t.Fatalf("state cookie %v does not match redirect %s", state, location)
+	}
+	if !strings.HasPrefix(location.String(), stub.URL+"/login/oauth/authorize?") || location.Query().Get("client_id") != fakeGitHubClientID {
+		t.Fatalf("redirected to %s", location)
+	}
+	return state
+}
+
+func githubCallback(t *testing.T, router *gin.Engine, state *http.Cookie, query url.Values) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/github/callback?"+query.Encode(), nil)
+	if state != nil {
+		req.AddCookie(state)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("callback response is not JSON: %s", w.Body.String())
+	}
+	return w.Code, body
+}
+
+// signInWithGitHub runs the whole flow as the GitHub user with login
+func signInWithGitHub(t *testing.T, stub *fakeGitHub, router *gin.Engine, login string) (int, map[string]interface{}) {
+	t.Helper()
+	state := beginGitHubLogin(t, stub, router)
+	return githubCallback(t, router, state, url.Values{"state": {state.Value}, "code": {stub.authorize(login)}})
+}
+
+func loggedInUserID(t *testing.T, status int, body map[string]interface{}) uint {
+	t.Helper()
+	if status != http.StatusOK || body["token"] == nil {
+		t.Fatalf("GitHub sign-in: status %d, %v", status, body)
+	}
+	who, _ := body["user"].(map[string]interface{})
+	id, _ := who["id"].(float64)
+	return uint(id)
+}
+
+func TestGitHubCallbackRejectsBadState(t *testing.T) {
+	setupTestDB(t)
+	stub := newFakeGitHub(t)
+	githubTestAccount(stub, 1, "octocat", verified("octocat@github.test"))
+	router := githubAuthRouter()
+	state := beginGitHubLogin(t, stub, router)
+
+	forged := &http.Cookie{Name: githubStateCookie, Value: "attacker-state"}
+	cases := []struct {
+		name   string
+		cookie *http.Cookie
+		state  string
+	}{
+		{"no cookie", nil, state.Value},
+		{"no state", state, ""},
+		{"another state", state, "attacker-state"},
+		{"another cookie", forged, state.Value},
+	}
+	for _, tc := range cases {
+		status, body := githubCallback(t, router, tc.cookie, url.Values{"state": {tc.state}, "code": {stub.authorize("octocat")}})
+		if status != http.StatusBadRequest || body["error"] != "Invalid OAuth state" {
+			t.Errorf("%s: status %d, %v", tc.name, status, body)
+		}
+	}
+	// No code was exchanged and no account created
+	if len(stub.tokens) != 0 {
+		t.Fatalf("%d codes exchanged despite a bad state", len(stub.tokens))
+	}
+	var count int64
+	config.DB.Model(&models.User{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("%d accounts created", count)
+	}
+
+	if status, _ := githubCallback(t, router, state, url.Values{"state": {state.Value}, "error": {"access_denied"}}); status != http.StatusBadRequest {
+		t.Fatalf("cancelled sign-in: status %d", status)
+	}
+	if status, _ := githubCallback(t, router, state, url.Values{"state": {state.Value}, "code": {"made-up"}}); status != http.StatusBadGateway {
+		t.Fatalf("bad code: status %d", status)
+	}
+}
+
+func TestGitHubCallbackCreatesAccount(t *testing.T) {
+	setupTestDB(t)
+	stub := newFakeGitHub(t)
+	githubTestAccount(stub, 583231, "octocat", verified("octocat@github.test"), unverified("other@github.test"))
+	// The GitHub login is taken as a username by someone else
+	createTestUser(t, "octocat")
+	router := githubAuthRouter()
+
+	status, body := signInWithGitHub(t, stub, router, "octocat")
+	userID := loggedInUserID(t, status, body)
+	if body["message"] != "Account created with GitHub" {
+		t.Fatalf("message = %v", body["message"])
+	}
+
+	var user models.User
+	config.DB.Preload("Profile").First(&user, userID)
+	if user.Username != "octocat-2" || user.Email != "octocat@github.test" || !user.EmailVerified || user.Password != "" {
+		t.Fatalf("created user %+v", user)
+	}
+	if user.Profile == nil || user.Profile.Github != "octocat" || user.Profile.FullName != "Octocat" || user.Profile.Bio != "Building things" {
+		t.Fatalf("created profile %+v", user.Profile)
+	}
+	var account models.OAuthAccount
+	if err := config.DB.Where("provider = ? AND provider_user_id = ?", providerGitHub, "583231").First(&account).Error; err != nil || account.UserID != userID {
+		t.Fatalf("GitHub identity not linked: %+v, %v", account, err)
+	}
+
+	// Signing in again, under a new login, reaches the same account by GitHub's user ID
+	stub.removeAccount("octocat")
+	githubTestAccount(stub, 583231, "octocat-renamed", verified("new@github.test"))
+	status, body = signInWithGitHub(t, stub, router, "octocat-renamed")
+	if id := loggedInUserID(t, status, body); id != userID || body["message"] != "Login successful" {
+		t.Fatalf("second sign-in reached user %d: %v", id, body["message"])
+	}
+	config.DB.First(&account, account.ID)
+	if account.Login != "octocat-renamed" {
+		t.Fatalf("linked login = %s", account.Login)
+	}
+	var count int64
+	config.DB.Model(&models.User{}).Count(&count)
+	if count != 2 {
+		t.Fatalf("%d users, want 2", count)
+	}
+}
+
+func TestGitHubCallbackLinksByVerifiedEmail(t *testing.T) {
+	setupTestDB(t)
+	stub := newFakeGitHub(t)
+	existing := createTestUser(t, "mona")
+	config.DB.Model(existing).Update("password", "hashed")
+	// Matched case-insensitively, and not only on the primary address
+	githubTestAccount(stub, 1, "mona-gh", verified("work@github.test"), verified("Mona@Example.com"))
+	router := githubAuthRouter()
+
+	status, body := signInWithGitHub(t, stub, router, "mona-gh")
+	if id := loggedInUserID(t, status, body); id != existing.ID || body["message"] != "Login successful" {
+		t.Fatalf("signed in as %d, want %d: %v", id, existing.ID, body["message"])
+	}
+
+	var user models.User
+	config.DB.Preload("Profile").First(&user, existing.ID)
+	if user.Password != "hashed" {
+		t.Fatal("linking a verified account dropped its password")
+	}
+	if user.Profile.Github != "mona-gh" {
+		t.Fatalf("profile github = %q, want it filled in", user.Profile.Github)
+	}
+}
+
+func TestGitHubCallbackIgnoresUnverifiedEmails(t *testing.T) {
+	setupTestDB(t)
+	stub := newFakeGitHub(t)
+	existing := createTestUser(t, "mona")
+	// An address GitHub has not verified must not reach the existing account
+	githubTestAccount(stub, 1, "impostor", verified("impostor@github.test"), unverified("mona@example.com"))
+	githubTestAccount(stub, 2, "nobody", unverified("nobody@github.test"))
+	router := githubAuthRouter()
+
+	status, body := signInWithGitHub(t, stub, router, "impostor")
+	if id := loggedInUserID(t, status, body); id == existing.ID {
+		t.Fatal("linked to an account by an unverified email")
+	}
+
+	status, body = signInWithGitHub(t, stub, router, "nobody")
+	if status != http.StatusBadRequest {
+		t.Fatalf("no verified email: status %d, %v", status, body)
+	}
+}
+
+func TestGitHubCallbackClaimsUnverifiedAccount(t *testing.T) {
+	setupTestDB(t)
+	stub := newFakeGitHub(t)
+	githubTestAccount(stub, 1, "octocat", verified("octocat@github.test"))
+	router := githubAuthRouter()
+
+	// Someone registered the address without owning it, and armed the account
+	squatter := models.User{Username: "squatter", Email: "octocat@github.test", Password: "hashed", TOTPEnabled: true, TOTPSecret: "secret"}
+	if err := createAccount(config.DB, &squatter, &models.Profile{}); err != nil {
+		t.Fatal(err)
+	}
+	config.DB.Create(&models.RecoveryCode{UserID: squatter.ID, CodeHash: "hash"})
+	config.DB.Create(&models.WebAuthnCredential{UserID: squatter.ID, Name: "Squatter's key", CredentialID: "credential", PublicKey: []byte{1}})
+	config.DB.Create(&models.PersonalAccessToken{UserID: squatter.ID, Name: "Squatter's bot", TokenHash: "hash", Scopes: models.ScopePostsWrite})
+	if _, err := issueTokenPair(config.DB, newLoginContext(), &squatter); err != nil {
+		t.Fatal(err)
+	}
+
+	// The 2FA the squatter set up does not stand between the owner and their account
+	status, body := signInWithGitHub(t, stub, router, "octocat")
+	if id := loggedInUserID(t, status, body); id != squatter.ID {
+		t.Fatalf("signed in as %d, want %d", id, squatter.ID)
+	}
+
+	var user models.User
+	config.DB.First(&user, squatter.ID)
+	if !user.EmailVerified || user.Password != "" || user.TOTPEnabled || user.TOTPSecret != "" {
+		t.Fatalf("credentials kept on the claimed account: %+v", user)
+	}
+	var recoveryCodes, passkeys, activeTokens, activeSessions, activeRefreshTokens int64
+	config.DB.Model(&models.RecoveryCode{}).Where("user_id = ?", user.ID).Count(&recoveryCodes)
+	config.DB.Model(&models.WebAuthnCredential{}).Where("user_id = ?", user.ID).Count(&passkeys)
+	config.DB.Model(&models.PersonalAccessToken{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Count(&activeTokens)
+	config.DB.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Count(&activeSessions)
+	config.DB.Model(&models.RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Count(&activeRefreshTokens)
+	if recoveryCodes != 0 || passkeys != 0 || activeTokens != 0 {
+		t.Fatalf("%d recovery codes, %d passkeys and %d personal access tokens kept", recoveryCodes, passkeys, activeTokens)
+	}
+	if activeSessions != 1 || activeRefreshTokens != 1 {
+		t.Fatalf("%d active sessions and %d refresh tokens, want only the owner's", activeSessions, activeRefreshTokens)
+	}
+
+	var event models.AuditEvent
+	if err := config.DB.Where("type = ? AND user_id = ?", models.AuditEmailVerified, user.ID).First(&event).Error; err != nil {
+		t.Fatalf("claim not audited: %v", err)
+	}
+	if !strings.Contains(event.Details, `"credentials_reset":true`) || !strings.Contains(event.Details, `"method":"github"`) {
+		t.Fatalf("audit details = %s", event.Details)
+	}
+}
+
+func TestGitHubCallbackHandsOffToTwoFactor(t *testing.T) {
+	setupTestDB(t)
+	stub := newFakeGitHub(t)
+	user := createTestUser(t, "mona")
+	config.DB.Model(user).Updates(map[string]interface{}{"totp_enabled": true, "totp_secret": "secret"})
+	githubTestAccount(stub, 1, "mona-gh", verified("mona@example.com"))
+	router := githubAuthRouter()
+
+	status, body := signInWithGitHub(t, stub, router, "mona-gh")
+	if status != http.StatusOK || body["two_factor_required"] != true || body["challenge_token"] == nil {
+		t.Fatalf("status %d, %v; want a 2FA challenge", status, body)
+	}
+	if body["token"] != nil || body["refresh_token"] != nil {
+		t.Fatal("tokens issued before the second factor")
+	}
+	var sessions int64
+	config.DB.Model(&models.Session{}).Count(&sessions)
+	if sessions != 0 {
+		t.Fatalf("%d sessions started before the second factor", sessions)
+	}
+
+	// The identity is linked all the same, so the next sign-in finds the account directly
+	var account models.OAuthAccount
+	if err := config.DB.Where("provider = ? AND provider_user_id = ?", providerGitHub, "1").First(&account).Error; err != nil || account.UserID != user.ID {
+		t.Fatalf("GitHub identity not linked: %+v, %v", account, err)
+	}
+}
@@ -0,0 +1,255 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"gitconnect-backend/github"
+)
+
+const (
+	fakeGitHubToken        = "server-token"
+	fakeGitHubClientID     = "stub-client"
+	fakeGitHubClientSecret = "stub-secret"
+)
+
+// fakeGitHubAccount is a GitHub user as the stub serves it
+type fakeGitHubAccount struct {
+	github.PublicUser
+	Repos  []github.Repository
+	Pinned []github.PinnedRepository
+	Emails []github.Email
+}
+
+// fakeGitHub stands in for the GitHub OAuth endpoints and REST and GraphQL APIs. Its setters make GitHub fail or
+// accounts change between requests; the handler holds mu while serving.
+type fakeGitHub struct {
+	*httptest.Server
+	mu          sync.Mutex
+	accounts    map[string]*fakeGitHubAccount // By lowercased login, as GitHub matches them
+	codes       map[string]int64              // Unused authorization codes, to account IDs
+	tokens      map[string]int64              // User access tokens, to account IDs
+	rateLimited bool
+	pinnedDown  bool
+	requests    int
+}
+
+// newFakeGitHub starts the stub and points github.Default at it for the rest of the test
+func newFakeGitHub(t *testing.T) *fakeGitHub {
+	t.Helper()
+	s := &fakeGitHub{
+		accounts: make(map[string]*fakeGitHubAccount),
+		codes:    make(map[string]int64),
+		tokens:   make(map[string]int64),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login/oauth/access_token", s.exchangeCode)
+	mux.HandleFunc("GET /user", s.authenticatedUser)
+	mux.HandleFunc("GET /user/emails", s.authenticatedEmails)
+	mux.HandleFunc("GET /users/{login}", s.getUser)
+	mux.HandleFunc("GET /users/{login}/repos", s.listRepos)
+	mux.HandleFunc("POST /graphql", s.graphQL)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		if s.rateLimited {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			writeGitHubError(w, http.StatusForbidden, "API rate limit exceeded for 127.0.0.1.")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+
+	previous := github.Default
+	github.Default = &github.Client{
+		WebURL:       s.URL,
+		APIURL:       s.URL,
+		Token:        fakeGitHubToken,
+		ClientID:     fakeGitHubClientID,
+		ClientSecret: fakeGitHubClientSecret,
+		HTTP:         s.Client(),
+	}
+	t.Cleanup(func() { github.Default = previous })
+	return s
+}
+
+// addAccount registers a GitHub user owning repos
+func (s *fakeGitHub) addAccount(id int64, login string, repos ...github.Repository) *fakeGitHubAccount {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account := &fakeGitHubAccount{Repos: repos}
+	account.ID = id
+	account.Login = login
+	account.Name = strings.ToUpper(login[:1]) + login[1:]
+	account.HTMLURL = "https://github.com/" + login
+	account.PublicRepos = len(repos)
+	s.accounts[strings.ToLower(login)] = account
+	return account
+}
+
+// authorize approves the app as the account with login would on GitHub, returning the code
+// GitHub sends back to the callback
+func (s *fakeGitHub) authorize(login string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := fmt.Sprintf("code-%d", len(s.codes)+len(s.tokens)+1)
+	s.codes[code] = s.accounts[strings.ToLower(login)].ID
+	return code
+}
+
+func (s *fakeGitHub) removeAccount(login string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.accounts, strings.ToLower(login))
+}
+
+func (s *fakeGitHub) setRateLimited(limited bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimited = limited
+}
+
+func (s *fakeGitHub) setPinnedDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pinnedDown = down
+}
+
+func (s *fakeGitHub) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *fakeGitHub) exchangeCode(w http.ResponseWriter, r *http.Request) {
+	if r.PostFormValue("client_id") != fakeGitHubClientID || r.PostFormValue("client_secret") != fakeGitHubClientSecret {
+		json.NewEncoder(w).Encode(map[string]string{"error": "incorrect_client_credentials", "error_description": "The client_id and/or client_secret passed are incorrect."})
+		return
+	}
+	// Codes work once, and GitHub reports a bad one with a 200
+	id, ok := s.codes[r.PostFormValue("code")]
+	if !ok {
+		json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code", "error_description": "The code passed is incorrect or expired."})
+		return
+	}
+	delete(s.codes, r.PostFormValue("code"))
+	token := fmt.Sprintf("gho_%d_%d", id, len(s.tokens)+1)
+	s.tokens[token] = id
+	json.NewEncoder(w).Encode(map[string]string{"access_token": token, "token_type": "bearer", "scope": "read:user,user:email"})
+}
+
+// tokenAccount returns the account a user access token was issued to, whatever its login is now
+func (s *fakeGitHub) tokenAccount(w http.ResponseWriter, r *http.Request) (*fakeGitHubAccount, bool) {
+	id, ok := s.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+	for _, account := range s.accounts {
+		if ok && account.ID == id {
+			return account, true
+		}
+	}
+	writeGitHubError(w, http.StatusUnauthorized, "Bad credentials")
+	return nil, false
+}
+
+func (s *fakeGitHub) authenticatedUser(w http.ResponseWriter, r *http.Request) {
+	if account, ok := s.tokenAccount(w, r); ok {
+		json.NewEncoder(w).Encode(account.User)
+	}
+}
+
+func (s *fakeGitHub) authenticatedEmails(w http.ResponseWriter, r *http.Request) {
+	if account, ok := s.tokenAccount(w, r); ok {
+		json.NewEncoder(w).Encode(append([]github.Email{}, account.Emails...))
+	}
+}
+
+func (s *fakeGitHub) getUser(w http.ResponseWriter, r *http.Request) {
+	account, ok := s.accounts[strings.ToLower(r.PathValue("login"))]
+	if !ok {
+		writeGitHubError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	json.NewEncoder(w).Encode(account.PublicUser)
+}
+
+func (s *fakeGitHub) listRepos(w http.ResponseWriter, r *http.Request) {
+	account, ok := s.accounts[strings.ToLower(r.PathValue("login"))]
+	if !ok {
+		writeGitHubError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if page < 1 || perPage < 1 {
+		writeGitHubError(w, http.StatusBadRequest, "bad paging")
+		return
+	}
+	start := min((page-1)*perPage, len(account.Repos))
+	end := min(start+perPage, len(account.Repos))
+	json.NewEncoder(w).Encode(account.Repos[start:end])
+}
+
+func (s *fakeGitHub) graphQL(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+fakeGitHubToken {
+		writeGitHubError(w, http.StatusUnauthorized, "This endpoint requires you to be authenticated.")
+		return
+	}
+	if s.pinnedDown {
+		writeGitHubError(w, http.StatusBadGateway, "Server Error")
+		return
+	}
+	var request struct {
+		Variables struct {
+			Login string `json:"login"`
+			First int    `json:"first"`
+		} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeGitHubError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+	account, ok := s.accounts[strings.ToLower(request.Variables.Login)]
+	if !ok {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data":   map[string]interface{}{"user": nil},
+			"errors": []map[string]string{{"type": "NOT_FOUND", "message": "Could not resolve to a User"}},
+		})
+		return
+	}
+
+	nodes := []map[string]interface{}{}
+	for i, repo := range account.Pinned {
+		if i == request.Variables.First {
+			break
+		}
+		node := map[string]interface{}{
+			"name":            repo.Name,
+			"nameWithOwner":   repo.FullName,
+			"description":     repo.Description,
+			"url":             repo.HTMLURL,
+			"stargazerCount":  repo.Stars,
+			"forkCount":       repo.Forks,
+			"primaryLanguage": nil,
+		}
+		if repo.Language != "" {
+			node["primaryLanguage"] = map[string]string{"name": repo.Language}
+		}
+		nodes = append(nodes, node)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": map[string]interface{}{"user": map[string]interface{}{"pinnedItems": map[string]interface{}{"nodes": nodes}}},
+	})
+}
+
+func writeGitHubError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
@@ -0,0 +1,294 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gitconnect-backend/audit"
+	"gitconnect-backend/config"
+	"gitconnect-backend/github"
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// How long synced GitHub data is served before it is refreshed, overridable with GITHUB_SYNC_INTERVAL
+	defaultGithubSyncInterval = 6 * time.Hour
+	// Profiles synced per run, so one run stays well inside GitHub's rate limits
+	githubSyncBatchSize = 10
+	// Retry delay after the first failure; it doubles with each further failure, up to the sync interval
+	githubSyncRetryDelay = 5 * time.Minute
+	// Languages kept per profile
+	maxGithubLanguages = 10
+)
+
+func githubSyncInterval() time.Duration {
+	if value := os.Getenv("GITHUB_SYNC_INTERVAL"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return defaultGithubSyncInterval
+}
+
+// githubRetryAt schedules the next attempt after a failed sync, backing off exponentially
+func githubRetryAt(now time.Time, failures int) time.Time {
+	interval := githubSyncInterval()
+	delay := githubSyncRetryDelay
+	for i := 1; i < failures && delay < interval; i++ {
+		delay *= 2
+	}
+	if delay > interval {
+		delay = interval
+	}
+	return now.Add(delay)
+}
+
+// SyncGithubProfile refreshes the cached GitHub data of one profile from the account named in its
+// github field, or drops the cache when the field is empty
+func SyncGithubProfile(profileID uint) error {
+	var profile models.Profile
+	if err := config.DB.First(&profile, profileID).Error; err != nil {
+		return err
+	}
+	if profile.Github == "" {
+		return config.DB.Where("profile_id = ?", profile.ID).Delete(&models.GithubProfile{}).Error
+	}
+
+	now := time.Now()
+	user, err := github.Default.GetUser(profile.Github)
+	var repos []github.Repository
+	if err == nil {
+		repos, err = github.Default.ListRepositories(profile.Github)
+	}
+	if err != nil {
+		if github.IsNotFound(err) && profile.GithubVerified {
+			revokeGithubVerification(&profile, 0)
+		}
+		recordGithubSyncFailure(&profile, now, err)
+		return err
+	}
+	if profile.GithubVerified && profile.GithubUserID != user.ID {
+		revokeGithubVerification(&profile, user.ID)
+	}
+
+	// Pinned repositories are a nicety: without a token there are none, and on a failure the
+	// previous ones are kept
+	pinned, pinnedErr := github.Default.GetPinnedRepositories(profile.Github)
+	keepPinned := false
+	if errors.Is(pinnedErr, github.ErrTokenRequired) {
+		pinned = nil
+	} else if pinnedErr != nil {
+		log.Println("❌ Failed to fetch pinned GitHub repositories:", pinnedErr)
+		keepPinned = true
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var cached models.GithubProfile
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("profile_id = ?", profile.ID).First(&cached).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		// A different account's pinned repositories are no use
+		if !strings.EqualFold(cached.Login, user.Login) {
+			keepPinned = false
+		}
+
+		cached.ProfileID = profile.ID
+		cached.Login = user.Login
+		cached.Name = user.Name
+		cached.AvatarURL = user.AvatarURL
+		cached.HTMLURL = user.HTMLURL
+		cached.Followers = user.Followers
+		cached.Following = user.Following
+		cached.PublicRepos = user.PublicRepos
+		cached.TotalStars = 0
+		for _, repo := range repos {
+			if !repo.Fork {
+				cached.TotalStars += repo.StargazersCount
+			}
+		}
+		cached.SyncedAt = &now
+		cached.NextSyncAt = now.Add(githubSyncInterval())
+		cached.Failures = 0
+		cached.LastError = ""
+		if err := tx.Omit(clause.Associations).Save(&cached).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("github_profile_id = ?", cached.ID).Delete(&models.GithubRepository{}).Error; err != nil {
+			return err
+		}
+		if rows := githubRepositoryRows(cached.ID, repos); len(rows) > 0 {
+			if err := tx.CreateInBatches(rows, 100).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("github_profile_id = ?", cached.ID).Delete(&models.GithubLanguage{}).Error; err != nil {
+			return err
+		}
+		if rows := githubLanguageRows(cached.ID, repos); len(rows) > 0 {
+			if err := tx.Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+
+		if keepPinned {
+			return nil
+		}
+		if err := tx.Where("github_profile_id = ?", cached.ID).Delete(&models.GithubPinnedRepository{}).Error; err != nil {
+			return err
+		}
+		if rows := githubPinnedRows(cached.ID, pinned); len(rows) > 0 {
+			return tx.Create(&rows).Error
+		}
+		return nil
+	})
+}
+
+// revokeGithubVerification drops the badge when the login no longer exists or now belongs to a
+// different GitHub account, i.e. it was renamed or deleted and taken by someone else
+func revokeGithubVerification(profile *models.Profile, githubUserID int64) {
+	profile.ClearGithubVerification()
+	if err := config.DB.Model(profile).Select("github_verified", "github_verified_at", "github_user_id").Updates(profile).Error; err != nil {
+		log.Println("❌ Failed to revoke GitHub verification:", err)
+		return
+	}
+	audit.Log(nil, models.AuditGithubUnverified, profile.UserID, audit.Details{"login": profile.Github, "github_user_id": githubUserID})
+}
+
+// recordGithubSyncFailure keeps the last good data but notes the error and when to try again.
+// An unknown GitHub user is not retried before the normal interval.
+func recordGithubSyncFailure(profile *models.Profile, now time.Time, syncErr error) {
+	cached := models.GithubProfile{ProfileID: profile.ID, Login: profile.Github}
+	if err := config.DB.Where("profile_id = ?", profile.ID).First(&cached).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Println("❌ Failed to record GitHub sync failure:", err)
+		return
+	}
+
+	cached.Failures++
+	cached.NextSyncAt = githubRetryAt(now, cached.Failures)
+	cached.LastError = "GitHub is unavailable, will retry"
+	if github.IsNotFound(syncErr) {
+		cached.NextSyncAt = now.Add(githubSyncInterval())
+		cached.LastError = "GitHub user not found"
+	}
+	if err := config.DB.Omit(clause.Associations).Save(&cached).Error; err != nil {
+		log.Println("❌ Failed to record GitHub sync failure:", err)
+	}
+}
+
+func githubRepositoryRows(githubProfileID uint, repos []github.Repository) []models.GithubRepository {
+	rows := make([]models.GithubRepository, 0, len(repos))
+	for _, repo := range repos {
+		row := models.GithubRepository{
+			GithubProfileID: githubProfileID,
+			Name:            repo.Name,
+			FullName:        repo.FullName,
+			Description:     repo.Description,
+			HTMLURL:         repo.HTMLURL,
+			Language:        repo.Language,
+			Stars:           repo.StargazersCount,
+			Forks:           repo.ForksCount,
+			Fork:            repo.Fork,
+			Archived:        repo.Archived,
+		}
+		if !repo.PushedAt.IsZero() {
+			pushedAt := repo.PushedAt
+			row.PushedAt = &pushedAt
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// githubLanguageRows ranks languages by how many owned, non-fork repositories use them
+func githubLanguageRows(githubProfileID uint, repos []github.Repository) []models.GithubLanguage {
+	counts := make(map[string]int)
+	for _, repo := range repos {
+		if !repo.Fork && repo.Language != "" {
+			counts[repo.Language]++
+		}
+	}
+	rows := make([]models.GithubLanguage, 0, len(counts))
+	for name, count := range counts {
+		rows = append(rows, models.GithubLanguage{GithubProfileID: githubProfileID, Name: name, Repositories: count})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Repositories != rows[j].Repositories {
+			return rows[i].Repositories > rows[j].Repositories
+		}
+		return rows[i].Name < rows[j].Name
+	})
+	if len(rows) > maxGithubLanguages {
+		rows = rows[:maxGithubLanguages]
+	}
+	return rows
+}
+
+func githubPinnedRows(githubProfileID uint, pinned []github.PinnedRepository) []models.GithubPinnedRepository {
+	rows := make([]models.GithubPinnedRepository, 0, len(pinned))
+	for i, repo := range pinned {
+		rows = append(rows, models.GithubPinnedRepository{
+			GithubProfileID: githubProfileID,
+			Position:        i,
+			Name:            repo.Name,
+			FullName:        repo.FullName,
+			Description:     repo.Description,
+			HTMLURL:         repo.HTMLURL,
+			Language:        repo.Language,
+			Stars:           repo.Stars,
+			Forks:           repo.Forks,
+		})
+	}
+	return rows
+}
+
+// SyncDueGithubProfiles syncs profiles that name a GitHub account and were never synced or are due,
+// oldest first. It stops early when GitHub rate-limits us.
+func SyncDueGithubProfiles() (int, error) {
+	var profileIDs []uint
+	if err := config.DB.Model(&models.Profile{}).
+		Joins("LEFT JOIN github_profiles ON github_profiles.profile_id = profiles.id").
+		Where("profiles.github <> '' AND (github_profiles.id IS NULL OR github_profiles.next_sync_at <= ?)", time.Now()).
+		Order("github_profiles.next_sync_at NULLS FIRST").
+		Limit(githubSyncBatchSize).
+		Pluck("profiles.id", &profileIDs).Error; err != nil {
+		return 0, err
+	}
+
+	synced := 0
+	for _, id := range profileIDs {
+		err := SyncGithubProfile(id)
+		if github.IsRateLimited(err) {
+			return synced, err
+		}
+		if err != nil {
+			if !github.IsNotFound(err) && !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("❌ GitHub sync of profile %d failed: %v", id, err)
+			}
+			continue
+		}
+		synced++
+	}
+	return synced, nil
+}
+
+// RunGithubSync syncs due GitHub profiles now and then every interval; run it in a goroutine
+func RunGithubSync(interval time.Duration) {
+	for {
+		synced, err := SyncDueGithubProfiles()
+		if err != nil {
+			log.Println("❌ GitHub sync failed:", err)
+		} else if synced > 0 {
+			log.Printf("🔄 Synced %d GitHub profile(s)", synced)
+		}
+		time.Sleep(interval)
+	}
+}
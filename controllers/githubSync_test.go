@@ -0,0 +1,290 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/github"
+	"gitconnect-backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+func githubRepo(name, language string, stars int, fork bool) github.Repository {
+	return github.Repository{
+		Name:            name,
+		FullName:        "octocat/" + name,
+		HTMLURL:         "https://github.com/octocat/" + name,
+		Language:        language,
+		StargazersCount: stars,
+		Fork:            fork,
+		PushedAt:        time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+// githubTestProfile creates a user whose profile names the GitHub login
+func githubTestProfile(t *testing.T, username, login string) *models.Profile {
+	t.Helper()
+	user := createTestUser(t, username)
+	var profile models.Profile
+	if err := config.DB.Where("user_id = ?", user.ID).First(&profile).Error; err != nil {
+		t.Fatal(err)
+	}
+	profile.Github = login
+	if err := config.DB.Save(&profile).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &profile
+}
+
+func loadGithubCache(t *testing.T, profileID uint) models.GithubProfile {
+	t.Helper()
+	var cached models.GithubProfile
+	if err := config.DB.Preload("Repositories").Preload("Languages").Preload("Pinned").
+		Where("profile_id = ?", profileID).First(&cached).Error; err != nil {
+		t.Fatalf("no cached GitHub data: %v", err)
+	}
+	return cached
+}
+
+func pinnedNames(cached models.GithubProfile) []string {
+	var names []string
+	for _, repo := range cached.Pinned {
+		names = append(names, fmt.Sprintf("%d:%s", repo.Position, repo.FullName))
+	}
+	return names
+}
+
+func TestSyncGithubProfile(t *testing.T) {
+	setupTestDB(t)
+	stub := newFakeGitHub(t)
+	account := stub.addAccount(583231, "octocat",
+		githubRepo("hello-world", "Go", 10, false),
+		githubRepo("spoon-knife", "Python", 3, false),
+		githubRepo("linguist", "Go", 1, false),
+		githubRepo("rust", "Rust", 100, true),
+	)
+	account.Followers = 42
+	account.Pinned = []github.PinnedRepository{
+		{Name: "hello-world", FullName: "octocat/hello-world", Language: "Go", Stars: 10},
+		{Name: "linux", FullName: "torvalds/linux", Language: "C", Stars: 180000},
+	}
+	// GitHub matches logins case-insensitively
+	profile := githubTestProfile(t, "octo", "OctoCat")
+
+	if err := SyncGithubProfile(profile.ID); err != nil {
+		t.Fatalf("SyncGithubProfile: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/profiles/:id", GetProfile)
+	status, body := doJSON(t, router, http.MethodGet, fmt.Sprintf("/profiles/%d", profile.ID), nil)
+	if status != http.StatusOK {
+		t.Fatalf("GetProfile: status %d, %v", status, body)
+	}
+	synced, _ := body["profile"].(map[string]interface{})["github_profile"].(map[string]interface{})
+	if synced["login"] != "octocat" || synced["followers"] != float64(42) || synced["public_repos"] != float64(4) {
+		t.Fatalf("github_profile = %v", synced)
+	}
+	// Forks count towards neither stars nor languages
+	if synced["total_stars"] != float64(14) {
+		t.Fatalf("total_stars = %v, want 14", synced["total_stars"])
+	}
+	languages := fmt.Sprint(synced["languages"])
+	if languages != "[map[name:Go repositories:2] map[name:Python repositories:1]]" {
+		t.Fatalf("languages = %s", languages)
+	}
+	repos, _ := synced["repositories"].([]interface{})
+	if len(repos) != 4 || repos[0].(map[string]interface{})["name"] != "rust" {
+		t.Fatalf("repositories = %v", repos)
+	}
+
+	cached := loadGithubCache(t, profile.ID)
+	if got := fmt.Sprint(pinnedNames(cached)); got != "[0:octocat/hello-world 1:torvalds/linux]" {
+		t.Fatalf("pinned = %s", got)
+	}
+	if cached.SyncedAt == nil || cached.Failures != 0 || cached.LastError != "" {
+		t.Fatalf("sync state = %v, %d, %q", cached.SyncedAt, cached.Failures, cached.LastError)
+	}
+	if due := time.Until(cached.NextSyncAt); due < defaultGithubSyncInterval-time.Minute || due > defaultGithubSyncInterval {
+		t.Fatalf("next sync in %v, want about %v", due, defaultGithubSyncInterval)
+	}
+}
+
+func TestSyncGithubProfileRefreshes(t *testing.T) {
+	setupTestDB(t)
+	stub := newFakeGitHub(t)
+	account := stub.addAccount(1, "octocat", githubRepo("hello-world", "Go", 10, false), githubRepo("linguist", "Ruby", 5, false))
+	account.Pinned = []github.PinnedRepository{{Name: "hello-world", FullName: "octocat/hello-world"}}
+	profile := githubTestProfile(t, "octo", "octocat")
+	if err := SyncGithubProfile(profile.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Repositories are replaced; pinned ones survive GraphQL being down
+	stub.addAccount(1, "octocat", githubRepo("linguist", "Ruby", 7, false))
+	stub.setPinnedDown(true)
+	if err := SyncGithubProfile(profile.ID); err != nil {
+		t.Fatal(err)
+	}
+	cached := loadGithubCache(t, profile.ID)
+	if len(cached.Repositories) != 1 || cached.TotalStars != 7 || len(cached.Languages) != 1 || cached.Languages[0].Name != "Ruby" {
+		t.Fatalf("after refresh: %d repositories, %d stars, languages %+v", len(cached.Repositories), cached.TotalStars, cached.Languages)
+	}
+	if got := fmt.Sprint(pinnedNames(cached)); got != "[0:octocat/hello-world]" {
+		t.Fatalf("pinned while GraphQL is down = %s", got)
+	}
+
+	// Without a token there is no way to know the pinned repositories, so none are shown
+	stub.setPinnedDown(false)
+	github.Default.Token = ""
+	if err := SyncGithubProfile(profile.ID); err != nil {
+		t.Fatal(err)
+	}
+	if cached := loadGithubCache(t, profile.ID); len(cached.Pinned) != 0 {
+		t.Fatalf("pinned without a token = %v", pinnedNames(cached))
+	}
+
+	// Clearing the handle drops the cache
+	config.DB.Model(profile).Update("github", "")
+	if err := SyncGithubProfile(profile.ID); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	config.DB.Model(&models.GithubProfile{}).Count(&count)
+	if count != 0 {
+		t.Fatal("cached GitHub data kept after the handle was cleared")
+	}
+}
+
+func TestSyncGithubProfilePaginates(t *testing.T) {
+	setupTestDB(t)
+	stub := newFakeGitHub(t)
+	var repos []github.Repository
+	// Three pages of 100
+	for i := 0; i < 250; i++ {
+		repos = append(repos, githubRepo(fmt.Sprintf("repo-%d", i), "Go", 1, false))
+	}
+	stub.addAccount(1, "octocat", repos...)
+	profile := githubTestProfile(t, "octo", "octocat")
+
+	if err := SyncGithubProfile(profile.ID); err != nil {
+		t.Fatal(err)
+	}
+	if cached := loadGithubCache(t, profile.ID); len(cached.Repositories) != len(repos) || cached.TotalStars != len(repos) {
+		t.Fatalf("%d repositories, %d stars; want %d", len(cached.Repositories), cached.TotalStars, len(repos))
+	}
+	// The user, three pages of repositories and the pinned ones
+	if got := stub.requestCount(); got != 5 {
+		t.Fatalf("%d requests to GitHub, want 5", got)
+	}
+}
+
+func TestSyncDueGithubProfilesStopsWhenRateLimited(t *testing.T) {
+	setupTestDB(t)
+	stub := newFakeGitHub(t)
+	for _, login := range []string{"alice", "bob", "carol"} {
+		stub.addAccount(int64(len(login)), login, githubRepo("hello-world", "Go", 1, false))
+		githubTestProfile(t, login, login)
+	}
+	githubTestProfile(t, "dave", "")
+
+	stub.setRateLimited(true)
+	synced, err := SyncDueGithubProfiles()
+	if !github.IsRateLimited(err) || synced != 0 {
+		t.Fatalf("SyncDueGithubProfiles = %d, %v; want a rate limit error", synced, err)
+	}
+	if got := stub.requestCount(); got != 1 {
+		t.Fatalf("%d requests after being rate-limited, want 1", got)
+	}
+	var failed []models.GithubProfile
+	config.DB.Find(&failed)
+	if len(failed) != 1 || failed[0].Failures != 1 || failed[0].LastError != "GitHub is unavailable, will retry" || failed[0].SyncedAt != nil {
+		t.Fatalf("failure recorded as %+v", failed)
+	}
+	if retry := time.Until(failed[0].NextSyncAt); retry < githubSyncRetryDelay-time.Minute || retry > githubSyncRetryDelay {
+		t.Fatalf("retry in %v, want about %v", retry, githubSyncRetryDelay)
+	}
+
+	// Once the limit resets the others are synced; the failed one waits for its retry
+	stub.setRateLimited(false)
+	if synced, err := SyncDueGithubProfiles(); err != nil || synced != 2 {
+		t.Fatalf("after the limit reset: %d, %v; want 2", synced, err)
+	}
+	if synced, err := SyncDueGithubProfiles(); err != nil || synced != 0 {
+		t.Fatalf("with nothing due: %d, %v", synced, err)
+	}
+}
+
+func TestSyncRevokesBadgeWhenLoginChangesHands(t *testing.T) {
+	setupTestDB(t)
+	stub := newFakeGitHub(t)
+	stub.addAccount(1, "octocat", githubRepo("hello-world", "Go", 10, false))
+	profile := githubTestProfile(t, "octo", "octocat")
+	config.DB.Model(profile).Updates(map[string]interface{}{"github_verified": true, "github_verified_at": time.Now(), "github_user_id": 1})
+
+	badge := func() models.Profile {
+		var current models.Profile
+		config.DB.First(&current, profile.ID)
+		return current
+	}
+	unverifiedEvents := func() int64 {
+		var count int64
+		config.DB.Model(&models.AuditEvent{}).Where("type = ? AND user_id = ?", models.AuditGithubUnverified, profile.UserID).Count(&count)
+		return count
+	}
+
+	if err := SyncGithubProfile(profile.ID); err != nil {
+		t.Fatal(err)
+	}
+	if current := badge(); !current.GithubVerified || current.GithubUserID != 1 {
+		t.Fatal("badge dropped while the login still belongs to the verified account")
+	}
+
+	// The owner renamed their account and someone else registered the old login
+	stub.addAccount(2, "octocat", githubRepo("squatted", "Go", 0, false))
+	if err := SyncGithubProfile(profile.ID); err != nil {
+		t.Fatal(err)
+	}
+	if current := badge(); current.GithubVerified || current.GithubVerifiedAt != nil || current.GithubUserID != 0 {
+		t.Fatalf("badge kept for a different GitHub account: %+v", current)
+	}
+	if unverifiedEvents() != 1 {
+		t.Fatal("revocation not audited")
+	}
+	if cached := loadGithubCache(t, profile.ID); len(cached.Repositories) != 1 || cached.Repositories[0].Name != "squatted" {
+		t.Fatalf("cache shows %+v", cached.Repositories)
+	}
+}
+
+func TestSyncRevokesBadgeWhenLoginDisappears(t *testing.T) {
+	setupTestDB(t)
+	stub := newFakeGitHub(t)
+	stub.addAccount(1, "octocat", githubRepo("hello-world", "Go", 10, false))
+	profile := githubTestProfile(t, "octo", "octocat")
+	config.DB.Model(profile).Updates(map[string]interface{}{"github_verified": true, "github_verified_at": time.Now(), "github_user_id": 1})
+	if err := SyncGithubProfile(profile.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	stub.removeAccount("octocat")
+	if err := SyncGithubProfile(profile.ID); !github.IsNotFound(err) {
+		t.Fatalf("err = %v, want not found", err)
+	}
+	var current models.Profile
+	config.DB.First(&current, profile.ID)
+	if current.GithubVerified || current.GithubUserID != 0 {
+		t.Fatal("badge kept for a login that no longer exists")
+	}
+
+	// The last good data is kept, and an unknown user is not retried early
+	cached := loadGithubCache(t, profile.ID)
+	if len(cached.Repositories) != 1 || cached.LastError != "GitHub user not found" || cached.Failures != 1 {
+		t.Fatalf("cache after the login disappeared: %d repositories, %q, %d failures", len(cached.Repositories), cached.LastError, cached.Failures)
+	}
+	if retry := time.Until(cached.NextSyncAt); retry < defaultGithubSyncInterval-time.Minute {
+		t.Fatalf("retry in %v, want about %v", retry, defaultGithubSyncInterval)
+	}
+}
@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"gitconnect-backend/audit"
+	"gitconnect-backend/config"
+	"gitconnect-backend/github"
+	"gitconnect-backend/models"
+	"gitconnect-backend/policy"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// How long a verification token can be published and checked
+	githubVerificationTTL = 24 * time.Hour
+	// File looked up in the repository named by the check request
+	githubVerificationFile = "gitconnect-verification.txt"
+	// Recently updated gists whose files are fetched when the token is not in their description
+	maxGistsChecked = 5
+)
+
+// GithubVerificationCheckRequest names the repository holding the verification file; it defaults to
+// the profile repository, github.com/<login>/<login>
+type GithubVerificationCheckRequest struct {
+	Repository string `json:"repository" binding:"omitempty,max=100,github_repo"`
+}
+
+// errTokenNotPublished means GitHub answered but the token is in neither place
+var errTokenNotPublished = errors.New("verification token not found")
+
+// findVerificationToken looks for the token in the login's public gists, then in the repository file.
+// It returns where the token was found.
+func findVerificationToken(challenge *models.GithubVerificationChallenge, repository string) (string, error) {
+	gists, err := github.Default.ListGists(challenge.Login)
+	if err != nil {
+		return "", err
+	}
+	// A gist last updated before the token existed cannot contain it
+	since := challenge.CreatedAt.Add(-time.Minute)
+	fetched := 0
+	for _, gist := range gists {
+		if !gist.Public || gist.UpdatedAt.Before(since) {
+			continue
+		}
+		if strings.Contains(gist.Description, challenge.Token) {
+			return "gist", nil
+		}
+		if fetched == maxGistsChecked {
+			continue
+		}
+		fetched++
+		full, err := github.Default.GetGist(gist.ID)
+		if err != nil {
+			return "", err
+		}
+		for _, file := range full.Files {
+			if strings.Contains(file.Content, challenge.Token) {
+				return "gist", nil
+			}
+		}
+	}
+
+	content, err := github.Default.GetFileContent(challenge.Login, repository, githubVerificationFile)
+	if github.IsNotFound(err) {
+		return "", errTokenNotPublished
+	}
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(content, challenge.Token) {
+		return "repository", nil
+	}
+	return "", errTokenNotPublished
+}
+
+// @Summary Start GitHub verification
+// @Description Issues a token proving ownership of the profile's GitHub login (only the profile owner, or a moderator). Publish it in a public gist, or in a gitconnect-verification.txt file on the default branch of github.com/<login>/<login> or another public repository, then call the check endpoint within 24 hours. Starting again replaces the previous token.
+// @Tags Profiles
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Security BearerAuth
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/github/verification [post]
+func StartGithubVerification(c *gin.Context) {
+	profile, ok := findProfile(c)
+	if !ok {
+		return
+	}
+	if !policy.Authorize(c, policy.ActionUpdate, profile) {
+		return
+	}
+	if profile.Github == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set a GitHub login on the profile first"})
+		return
+	}
+	if profile.GithubVerified {
+		c.JSON(http.StatusConflict, gin.H{"error": "GitHub account is already verified"})
+		return
+	}
+
+	secret, err := utils.GenerateOpaqueToken(18)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start verification"})
+		return
+	}
+	challenge := models.GithubVerificationChallenge{
+		ProfileID: profile.ID,
+		Login:     profile.Github,
+		Token:     "gitconnect-verification-" + secret,
+		ExpiresAt: time.Now().Add(githubVerificationTTL),
+	}
+	if err := config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "profile_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"login", "token", "expires_at", "created_at", "updated_at"}),
+	}).Create(&challenge).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start verification"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"login":      challenge.Login,
+		"token":      challenge.Token,
+		"expires_at": challenge.ExpiresAt,
+		"instructions": "Publish the token in a public gist, or in a file named " + githubVerificationFile +
+			" on the default branch of github.com/" + challenge.Login + "/" + challenge.Login +
+			" (or another public repository you name when checking), then call the check endpoint.",
+	})
+}
+
+// @Summary Check GitHub verification
+// @Description Looks for the published verification token in the login's recently updated public gists and in gitconnect-verification.txt of the named repository (default github.com/<login>/<login>). On success the profile is marked verified; the token can be removed from GitHub afterwards.
+// @Tags Profiles
+// @Accept json
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param request body GithubVerificationCheckRequest false "Repository holding the verification file"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 410 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Router /api/profiles/{id}/github/verification/check [post]
+func CheckGithubVerification(c *gin.Context) {
+	profile, ok := findProfile(c)
+	if !ok {
+		return
+	}
+	if !policy.Authorize(c, policy.ActionUpdate, profile) {
+		return
+	}
+
+	// The body is optional
+	var input GithubVerificationCheckRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var challenge models.GithubVerificationChallenge
+	if err := config.DB.Where("profile_id = ?", profile.ID).First(&challenge).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No verification in progress; start one first"})
+		return
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "Verification token has expired; start a new verification"})
+		return
+	}
+	if !strings.EqualFold(challenge.Login, profile.Github) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The profile's GitHub login has changed; start a new verification"})
+		return
+	}
+
+	user, err := github.Default.GetUser(challenge.Login)
+	if github.IsNotFound(err) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "GitHub user not found"})
+		return
+	}
+	if err != nil {
+		log.Println("❌ GitHub verification check failed:", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "GitHub is unavailable, try again later"})
+		return
+	}
+
+	repository := input.Repository
+	if repository == "" {
+		repository = user.Login
+	}
+	method, err := findVerificationToken(&challenge, repository)
+	if errors.Is(err, errTokenNotPublished) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "Verification token not found in a public gist or in " + githubVerificationFile + " of " + user.Login + "/" + repository,
+		})
+		return
+	}
+	if err != nil {
+		log.Println("❌ GitHub verification check failed:", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "GitHub is unavailable, try again later"})
+		return
+	}
+
+	now := time.Now()
+	profile.Github = user.Login // GitHub's own casing
+	profile.GithubVerified = true
+	profile.GithubVerifiedAt = &now
+	profile.GithubUserID = user.ID
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(profile).Select("github", "github_verified", "github_verified_at", "github_user_id").Updates(profile).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&challenge).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save verification"})
+		return
+	}
+
+	audit.Log(c, models.AuditGithubVerified, profile.UserID, audit.Details{"login": user.Login, "method": method})
+	c.JSON(http.StatusOK, gin.H{"message": "GitHub account verified", "profile": profile})
+}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gitconnect-backend/imaging"
+	"gitconnect-backend/storage"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// newImageKey names a fresh blob under prefix; every upload gets a new key so caches never serve
+// an old image under a new name
+func newImageKey(prefix string, ext string) (string, error) {
+	name, err := utils.GenerateOpaqueToken(12)
+	if err != nil {
+		return "", err
+	}
+	return prefix + "/" + name + ext, nil
+}
+
+// imageVariantKey names the thumbnail or preview of the given size stored beside the original at key
+func imageVariantKey(key string, size int) string {
+	ext := path.Ext(key)
+	return fmt.Sprintf("%s_%d%s", strings.TrimSuffix(key, ext), size, ext)
+}
+
+// readUploadedImage reads the multipart field "image" of at most maxBytes; it writes the error
+// response itself
+func readUploadedImage(c *gin.Context, maxBytes int64) ([]byte, bool) {
+	// Leave room for the multipart framing around the file itself
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+64<<10)
+
+	file, header, err := c.Request.FormFile("image")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image is too large"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Image file is required"})
+		return nil, false
+	}
+	defer file.Close()
+	if header.Size > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image is too large"})
+		return nil, false
+	}
+
+	var data bytes.Buffer
+	if _, err := io.Copy(&data, io.LimitReader(file, maxBytes+1)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read image"})
+		return nil, false
+	}
+	if int64(data.Len()) > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image is too large"})
+		return nil, false
+	}
+	return data.Bytes(), true
+}
+
+// processUploadedImage runs an upload through process (imaging.Process or ProcessPreviews); it
+// writes the error response itself. Never store the upload itself: decoding proves it is an
+// image, and re-encoding drops its metadata.
+func processUploadedImage(c *gin.Context, data []byte, sizes []int, process func([]byte, []int) (*imaging.Result, error)) (*imaging.Result, bool) {
+	processed, err := process(data, sizes)
+	if errors.Is(err, imaging.ErrImageTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image dimensions are too large"})
+		return nil, false
+	}
+	if errors.Is(err, imaging.ErrUnsupportedImage) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Images must be JPEG, PNG, GIF or WebP"})
+		return nil, false
+	}
+	if err != nil {
+		log.Println("❌ Failed to process image:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
+		return nil, false
+	}
+	return processed, true
+}
+
+// storeImage writes the processed original under a new key in prefix, with its variants beside it,
+// removing what was written if any of them fails
+func storeImage(ctx context.Context, prefix string, processed *imaging.Result, sizes []int) (string, error) {
+	key, err := newImageKey(prefix, processed.Original.Ext)
+	if err != nil {
+		return "", err
+	}
+	if err := storage.Default.Put(ctx, key, processed.Original.Data, processed.Original.ContentType); err != nil {
+		return "", err
+	}
+	for _, size := range sizes {
+		variant := processed.Thumbnails[size]
+		if err := storage.Default.Put(ctx, imageVariantKey(key, size), variant.Data, variant.ContentType); err != nil {
+			deleteImage(ctx, key, sizes)
+			return "", err
+		}
+	}
+	return key, nil
+}
+
+// deleteImage removes an image and its variants once they are no longer referenced; failures only
+// leave orphaned blobs
+func deleteImage(ctx context.Context, key string, sizes []int) {
+	if key == "" {
+		return
+	}
+	keys := []string{key}
+	for _, size := range sizes {
+		keys = append(keys, imageVariantKey(key, size))
+	}
+	for _, k := range keys {
+		if err := storage.Default.Delete(ctx, k); err != nil {
+			log.Println("❌ Failed to delete image:", err)
+		}
+	}
+}
+
+// serveImage streams the image at key, or its variant named by the size query parameter. Images
+// stored before variants existed only have the original, which is served instead.
+func serveImage(c *gin.Context, key string, sizes []int, notFound string) {
+	variant := key
+	if value := c.Query("size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || !slices.Contains(sizes, size) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "size must be one of " + joinSizes(sizes)})
+			return
+		}
+		variant = imageVariantKey(key, size)
+	}
+
+	object, err := storage.Default.Get(c.Request.Context(), variant)
+	if errors.Is(err, storage.ErrNotFound) && variant != key {
+		object, err = storage.Default.Get(c.Request.Context(), key)
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+		return
+	}
+	if err != nil {
+		log.Println("❌ Failed to load image:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load image"})
+		return
+	}
+	defer object.Body.Close()
+
+	c.DataFromReader(http.StatusOK, object.Size, object.ContentType, object.Body, map[string]string{
+		"Cache-Control":          "public, max-age=300",
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// joinSizes lists sizes as "64, 256 or 512"
+func joinSizes(sizes []int) string {
+	names := make([]string, len(sizes))
+	for i, size := range sizes {
+		names[i] = strconv.Itoa(size)
+	}
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitconnect-backend/audit"
+	"gitconnect-backend/mailer"
+	"gitconnect-backend/models"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	purposeUnlockAccount = "unlock_account"
+	unlockLinkTTL        = 24 * time.Hour
+
+	tooManyFailedAttempts = "Too many failed attempts. Please wait before trying again."
+)
+
+// AccountThrottle limits guesses against a single account (login email, 2FA codes).
+// Swap its Store for a shared implementation when running more than one instance.
+var AccountThrottle = utils.NewThrottle(utils.NewMemoryAttemptStore(24*time.Hour), utils.ThrottlePolicy{
+	FreeAttempts:    3,
+	BaseDelay:       time.Second,
+	MaxDelay:        time.Minute,
+	LockoutAfter:    10,
+	LockoutDuration: 30 * time.Minute,
+	ResetAfter:      24 * time.Hour,
+})
+
+// IPThrottle limits how fast one client address can fail logins across all accounts
+var IPThrottle = utils.NewThrottle(utils.NewMemoryAttemptStore(time.Hour), utils.ThrottlePolicy{
+	FreeAttempts: 20,
+	BaseDelay:    time.Second,
+	MaxDelay:     5 * time.Minute,
+	ResetAfter:   time.Hour,
+})
+
+func loginAccountKey(email string) string {
+	return "login:account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func loginIPKey(c *gin.Context) string {
+	return "login:ip:" + c.ClientIP()
+}
+
+func twoFactorKey(userID uint) string {
+	return fmt.Sprintf("2fa:user:%d", userID)
+}
+
+// allowAttempt checks the throttles for the given keys (either may be empty) before any expensive work is done.
+// It writes a 423 (locked) or 429 (slow down) response and returns false when the attempt is refused.
+func allowAttempt(c *gin.Context, accountKey string, ipKey string) bool {
+	if ipKey != "" {
+		decision, err := IPThrottle.Check(ipKey)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+			return false
+		}
+		if !decision.Allowed {
+			rejectAttempt(c, decision, tooManyFailedAttempts)
+			return false
+		}
+	}
+
+	if accountKey != "" {
+		decision, err := AccountThrottle.Check(accountKey)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+			return false
+		}
+		if !decision.Allowed {
+			rejectAttempt(c, decision, tooManyFailedAttempts)
+			return false
+		}
+	}
+	return true
+}
+
+// rejectAttempt writes the response for a refused attempt. tooMany explains a 429 in terms of what
+// the throttle counts, which is not always failures.
+func rejectAttempt(c *gin.Context, decision utils.ThrottleDecision, tooMany string) {
+	seconds := int(math.Ceil(decision.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+
+	if decision.Locked {
+		c.JSON(http.StatusLocked, gin.H{
+			"error":       "Account temporarily locked after too many failed attempts. Check your email for an unlock link or try again later.",
+			"retry_after": seconds,
+		})
+		return
+	}
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       tooMany,
+		"retry_after": seconds,
+	})
+}
+
+// recordFailedLogin counts a failure against the account and IP, emailing an unlock link
+// to a real account the moment it becomes locked
+func recordFailedLogin(c *gin.Context, email string, user *models.User) {
+	locked, _ := AccountThrottle.Fail(loginAccountKey(email))
+	_, _ = IPThrottle.Fail(loginIPKey(c))
+
+	var userID uint
+	if user != nil {
+		userID = user.ID
+	}
+	audit.Log(c, models.AuditLoginFailed, userID, audit.Details{"email": email})
+
+	if locked && user != nil {
+		audit.Log(c, models.AuditAccountLocked, user.ID, nil)
+		_ = sendUnlockEmail(user)
+	}
+}
+
+func sendUnlockEmail(user *models.User) error {
+	token, err := utils.GeneratePurposeToken(user.ID, user.Email, purposeUnlockAccount, unlockLinkTTL)
+	if err != nil {
+		return err
+	}
+
+	link := publicURL("/api/auth/unlock", url.Values{"token": {token}})
+	return mailer.Default.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your GitConnect account has been locked",
+		Body: fmt.Sprintf("Hi %s,\n\nWe locked your GitConnect account after too many failed sign-in attempts. "+
+			"If that was you, open the link below to unlock it now:\n\n%s\n\n"+
+			"If it was not you, consider resetting your password.\n",
+			user.Username, link),
+	})
+}
+
+// @Summary Unlock account
+// @Description Clears a login lockout using the link emailed when the account was locked
+// @Tags Auth
+// @Produce json
+// @Param token query string true "Unlock token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /api/auth/unlock [get]
+func UnlockAccount(c *gin.Context) {
+	claims, err := utils.ValidatePurposeToken(c.Query("token"), purposeUnlockAccount)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired unlock link"})
+		return
+	}
+
+	if err := AccountThrottle.Reset(loginAccountKey(claims.Email)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock account"})
+		return
+	}
+	audit.Log(c, models.AuditAccountUnlocked, claims.UserID, audit.Details{"via": "email"})
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked. You can sign in again."})
+}
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"gitconnect-backend/mailer"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+var unlockLinkToken = regexp.MustCompile(`/api/auth/unlock\?token=([A-Za-z0-9._-]+)`)
+
+// setupThrottleTest swaps in fresh throttles with the given policies, so counts start at zero and
+// backoff can be made long enough to observe
+func setupThrottleTest(t *testing.T, account, ip utils.ThrottlePolicy) (*gin.Engine, *mailer.MemoryMailer) {
+	t.Helper()
+	setupTestDB(t)
+	previousAccount, previousIP, previousMailer := AccountThrottle, IPThrottle, mailer.Default
+	AccountThrottle = utils.NewThrottle(utils.NewMemoryAttemptStore(time.Hour), account)
+	IPThrottle = utils.NewThrottle(utils.NewMemoryAttemptStore(time.Hour), ip)
+	outbox := mailer.NewMemoryMailer()
+	mailer.Default = outbox
+	t.Cleanup(func() { AccountThrottle, IPThrottle, mailer.Default = previousAccount, previousIP, previousMailer })
+
+	router := gin.New()
+	router.POST("/api/auth/login", Login)
+	router.GET("/api/auth/unlock", UnlockAccount)
+	return router, outbox
+}
+
+func attemptLogin(t *testing.T, router *gin.Engine, email, password string) (int, map[string]interface{}) {
+	t.Helper()
+	return doJSON(t, router, http.MethodPost, "/api/auth/login", gin.H{"email": email, "password": password})
+}
+
+func TestLoginBacksOffAfterFreeAttempts(t *testing.T) {
+	router, _ := setupThrottleTest(t,
+		utils.ThrottlePolicy{FreeAttempts: 3, BaseDelay: time.Hour, ResetAfter: 24 * time.Hour},
+		utils.ThrottlePolicy{})
+	user := createTestUser(t, "alice")
+	setPassword(t, user, "correct-password")
+
+	// The first failure past the free ones starts the backoff
+	for i := 1; i <= 4; i++ {
+		if status, _ := attemptLogin(t, router, user.Email, "wrong-password"); status != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: status %d, want 401", i, status)
+		}
+	}
+	status, body := attemptLogin(t, router, user.Email, "wrong-password")
+	if status != http.StatusTooManyRequests || body["error"] != tooManyFailedAttempts || body["retry_after"].(float64) < 3500 {
+		t.Fatalf("attempt during backoff: status %d, %v", status, body)
+	}
+	// The right password has to wait too, or the backoff would confirm guesses
+	if status, _ := attemptLogin(t, router, user.Email, "correct-password"); status != http.StatusTooManyRequests {
+		t.Fatalf("correct password during backoff: status %d, want 429", status)
+	}
+	// Counted per account, case-insensitively, and unknown emails are counted the same way
+	if status, _ := attemptLogin(t, router, "ALICE@example.com", "wrong-password"); status != http.StatusTooManyRequests {
+		t.Fatalf("same account in other case: status %d, want 429", status)
+	}
+	if status, _ := attemptLogin(t, router, "bob@example.com", "wrong-password"); status != http.StatusUnauthorized {
+		t.Fatalf("another account: status %d, want 401", status)
+	}
+}
+
+func TestLoginLocksAccountUntilUnlocked(t *testing.T) {
+	router, outbox := setupThrottleTest(t,
+		utils.ThrottlePolicy{FreeAttempts: 10, LockoutAfter: 5, LockoutDuration: time.Hour, ResetAfter: 24 * time.Hour},
+		utils.ThrottlePolicy{})
+	user := createTestUser(t, "alice")
+	setPassword(t, user, "correct-password")
+
+	for i := 1; i <= 5; i++ {
+		if status, _ := attemptLogin(t, router, user.Email, "wrong-password"); status != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: status %d, want 401", i, status)
+		}
+	}
+	status, body := attemptLogin(t, router, user.Email, "wrong-password")
+	if status != http.StatusLocked {
+		t.Fatalf("locked account: status %d, %v", status, body)
+	}
+	if status, _ := attemptLogin(t, router, user.Email, "correct-password"); status != http.StatusLocked {
+		t.Fatalf("correct password while locked: status %d, want 423", status)
+	}
+
+	// Locking sent the owner one unlock link, and the link lifts the lock
+	if len(outbox.Messages()) != 1 {
+		t.Fatalf("%d emails sent, want one unlock link", len(outbox.Messages()))
+	}
+	message, _ := outbox.Last(user.Email)
+	match := unlockLinkToken.FindStringSubmatch(message.Body)
+	if match == nil {
+		t.Fatalf("no unlock link in %q", message.Body)
+	}
+	if status, _ := doJSON(t, router, http.MethodGet, "/api/auth/unlock?token=x", nil); status != http.StatusBadRequest {
+		t.Fatalf("bad unlock link: status %d, want 400", status)
+	}
+	if status, _ := doJSON(t, router, http.MethodGet, "/api/auth/unlock?token="+match[1], nil); status != http.StatusOK {
+		t.Fatalf("unlock: status %d", status)
+	}
+	if status, _ := attemptLogin(t, router, user.Email, "wrong-password"); status != http.StatusUnauthorized {
+		t.Fatalf("after unlock: status %d, want 401", status)
+	}
+
+	// Logging in clears the failures counted so far
+	if status, body := attemptLogin(t, router, user.Email, "correct-password"); status != http.StatusOK {
+		t.Fatalf("correct password after unlock: status %d, %v", status, body)
+	}
+	for i := 1; i <= 5; i++ {
+		if status, _ := attemptLogin(t, router, user.Email, "wrong-password"); status != http.StatusUnauthorized {
+			t.Fatalf("attempt %d after login: status %d, want 401", i, status)
+		}
+	}
+}
+
+func TestLoginThrottlesClientAddress(t *testing.T) {
+	router, _ := setupThrottleTest(t,
+		utils.ThrottlePolicy{FreeAttempts: 10},
+		utils.ThrottlePolicy{FreeAttempts: 2, BaseDelay: time.Hour, ResetAfter: time.Hour})
+
+	// Spreading guesses over many accounts does not get around the limit for one address
+	for i, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if status, _ := attemptLogin(t, router, email, "guess"); status != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: status %d, want 401", i+1, status)
+		}
+	}
+	if status, _ := attemptLogin(t, router, "d@example.com", "guess"); status != http.StatusTooManyRequests {
+		t.Fatalf("fourth account from one address: status %d, want 429", status)
+	}
+}
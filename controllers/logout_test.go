@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// setupLogoutTest serves the logout endpoints and a protected route behind the real AuthMiddleware,
+// with revocations kept in the test database
+func setupLogoutTest(t *testing.T) (*gin.Engine, *models.User) {
+	t.Helper()
+	db := setupTestDB(t)
+	previous := utils.Revocations
+	utils.Revocations = utils.NewDBRevocationStore(db)
+	t.Cleanup(func() { utils.Revocations = previous })
+
+	router := gin.New()
+	router.POST("/api/auth/refresh", RefreshToken)
+	router.POST("/api/auth/logout", middlewares.AuthMiddleware(), Logout)
+	router.POST("/api/auth/logout-all", middlewares.AuthMiddleware(), LogoutAll)
+	router.GET("/protected", middlewares.AuthMiddleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("user_id")})
+	})
+	return router, createTestUser(t, "alice")
+}
+
+// doAuthorized calls the router with an access token
+func doAuthorized(router http.Handler, method, path, token string) int {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func login(t *testing.T, user *models.User) tokenPair {
+	t.Helper()
+	pair, err := issueTokenPair(config.DB, newLoginContext(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pair
+}
+
+func TestLogoutRevokesOnlyThatLogin(t *testing.T) {
+	router, user := setupLogoutTest(t)
+	phone, laptop := login(t, user), login(t, user)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/logout", jsonBody(t, LogoutRequest{RefreshToken: phone.RefreshToken}))
+	req.Header.Set("Authorization", "Bearer "+phone.AccessToken)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("logout: status %d, %s", w.Code, w.Body)
+	}
+
+	if status := doAuthorized(router, http.MethodGet, "/protected", phone.AccessToken); status != http.StatusUnauthorized {
+		t.Fatalf("logged out access token: status %d, want 401", status)
+	}
+	if status, _ := refresh(t, router, phone.RefreshToken); status != http.StatusUnauthorized {
+		t.Fatalf("logged out refresh token: status %d, want 401", status)
+	}
+	if status := doAuthorized(router, http.MethodGet, "/protected", laptop.AccessToken); status != http.StatusOK {
+		t.Fatalf("other login's access token: status %d, want 200", status)
+	}
+	if status, _ := refresh(t, router, laptop.RefreshToken); status != http.StatusOK {
+		t.Fatalf("other login's refresh token: status %d, want 200", status)
+	}
+
+	// The revocation is in the database, so another instance would refuse the token too
+	var revoked int64
+	config.DB.Model(&models.RevokedToken{}).Where("user_id = ?", user.ID).Count(&revoked)
+	if revoked != 1 {
+		t.Fatalf("%d revoked_tokens rows, want 1", revoked)
+	}
+}
+
+func TestLogoutWithoutRefreshToken(t *testing.T) {
+	router, user := setupLogoutTest(t)
+	pair := login(t, user)
+
+	if status := doAuthorized(router, http.MethodPost, "/api/auth/logout", pair.AccessToken); status != http.StatusOK {
+		t.Fatalf("logout with no body: status %d", status)
+	}
+	if status := doAuthorized(router, http.MethodGet, "/protected", pair.AccessToken); status != http.StatusUnauthorized {
+		t.Fatalf("logged out access token: status %d, want 401", status)
+	}
+	if status := doAuthorized(router, http.MethodPost, "/api/auth/logout", "not-a-jwt"); status != http.StatusUnauthorized {
+		t.Fatalf("logout with a bad token: status %d, want 401", status)
+	}
+}
+
+func TestLogoutAllRevokesEveryLogin(t *testing.T) {
+	router, user := setupLogoutTest(t)
+	other := createTestUser(t, "bob")
+	phone, laptop, bystander := login(t, user), login(t, user), login(t, other)
+
+	// iat has one-second precision and tokens from the cutoff's second are spared, so let it tick over
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+
+	if status := doAuthorized(router, http.MethodPost, "/api/auth/logout-all", phone.AccessToken); status != http.StatusOK {
+		t.Fatalf("logout-all: status %d", status)
+	}
+	for name, pair := range map[string]tokenPair{"phone": phone, "laptop": laptop} {
+		if status := doAuthorized(router, http.MethodGet, "/protected", pair.AccessToken); status != http.StatusUnauthorized {
+			t.Errorf("%s access token: status %d, want 401", name, status)
+		}
+		if status, _ := refresh(t, router, pair.RefreshToken); status != http.StatusUnauthorized {
+			t.Errorf("%s refresh token: status %d, want 401", name, status)
+		}
+	}
+
+	// Other users are untouched, and logging in again works straight away
+	if status := doAuthorized(router, http.MethodGet, "/protected", bystander.AccessToken); status != http.StatusOK {
+		t.Fatalf("other user's access token: status %d, want 200", status)
+	}
+	if status := doAuthorized(router, http.MethodGet, "/protected", login(t, user).AccessToken); status != http.StatusOK {
+		t.Fatalf("new login after logout-all: status %d, want 200", status)
+	}
+}
@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/mailer"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	purposeMagicLink = "magic_link"
+	magicLinkTTL     = 15 * time.Minute
+
+	tooManyMagicLinks = "Too many sign-in links requested for this address. Please wait before asking for another."
+)
+
+var errMagicLinkInvalid = errors.New("invalid magic link")
+
+// MagicLinkThrottle limits how often a sign-in link can be emailed to one address: a few
+// straight away, then with a growing wait between them
+var MagicLinkThrottle = utils.NewThrottle(utils.NewMemoryAttemptStore(time.Hour), utils.ThrottlePolicy{
+	FreeAttempts: 3,
+	BaseDelay:    time.Minute,
+	MaxDelay:     15 * time.Minute,
+	ResetAfter:   time.Hour,
+})
+
+// MagicLinkRequest asks for a sign-in link
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// MagicLinkVerifyRequest exchanges a sign-in link for tokens
+type MagicLinkVerifyRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+func magicLinkKey(email string) string {
+	return "magic:email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// @Summary Request a magic sign-in link
+// @Description Emails a single-use sign-in link that is valid for 15 minutes. Always responds the same way so emails cannot be enumerated; repeated requests for one address are rate limited.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body MagicLinkRequest true "Account email"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /api/auth/magic-link [post]
+func RequestMagicLink(c *gin.Context) {
+	var input MagicLinkRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Counted for unknown addresses too, so the limit itself reveals nothing
+	key := magicLinkKey(input.Email)
+	decision, err := MagicLinkThrottle.Check(key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send sign-in link"})
+		return
+	}
+	if !decision.Allowed {
+		rejectAttempt(c, decision, tooManyMagicLinks)
+		return
+	}
+	_, _ = MagicLinkThrottle.Fail(key)
+
+	response := gin.H{"message": "If an account exists for that email, a sign-in link has been sent"}
+
+	var user models.User
+	if err := config.DB.Where("email = ?", input.Email).First(&user).Error; err != nil || user.IsSuspended() {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	if err := sendMagicLink(&user); err != nil {
+		log.Println("❌ Failed to send magic link:", err)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// sendMagicLink replaces any outstanding sign-in link with a new one and mails it
+func sendMagicLink(user *models.User) error {
+	token, err := utils.GeneratePurposeToken(user.ID, user.Email, purposeMagicLink, magicLinkTTL)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Only the most recent link works
+		if err := tx.Model(&models.MagicLinkToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.MagicLinkToken{
+			UserID:    user.ID,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: now.Add(magicLinkTTL),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	link := frontendURL("/magic-link", url.Values{"token": {token}})
+	return mailer.Default.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your GitConnect sign-in link",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to sign in to GitConnect:\n\n%s\n\n"+
+			"The link expires in %d minutes and can only be used once. If you did not ask for it, you can ignore this email.\n",
+			user.Username, link, int(magicLinkTTL.Minutes())),
+	})
+}
+
+// @Summary Sign in with a magic link
+// @Description Exchanges the token from a sign-in link for the same tokens as a password login. Accounts with 2FA get a 2FA challenge instead. If the account never verified its email, its password, 2FA and passkeys are dropped and its other sessions and tokens are signed out, since whoever registered it had not proven they own the address.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body MagicLinkVerifyRequest true "Token from the sign-in link"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/magic-link/verify [post]
+func VerifyMagicLink(c *gin.Context) {
+	var input MagicLinkVerifyRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := utils.ValidatePurposeToken(input.Token, purposeMagicLink)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired sign-in link"})
+		return
+	}
+
+	var user models.User
+	claimed := false
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Marking the link used in the same statement that checks it makes it single-use
+		result := tx.Model(&models.MagicLinkToken{}).
+			Where("token_hash = ? AND user_id = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(input.Token), claims.UserID, time.Now()).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errMagicLinkInvalid
+		}
+
+		if err := tx.First(&user, claims.UserID).Error; err != nil {
+			return errMagicLinkInvalid
+		}
+		// A link sent to an address the user has since changed away from is stale
+		if !strings.EqualFold(user.Email, claims.Email) {
+			return errMagicLinkInvalid
+		}
+
+		// Opening the link proves control of the mailbox, which the account never did
+		if !user.EmailVerified {
+			claimed = true
+			return claimUnverifiedAccount(tx, &user)
+		}
+		return nil
+	})
+	if errors.Is(err, errMagicLinkInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired sign-in link"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	middlewares.ForgetUser(user.ID)
+	if claimed {
+		if err := revokeClaimedAccount(c, &user, "magic_link"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+			return
+		}
+	}
+
+	// As good as the unlock link, and the address can ask for links again
+	_ = AccountThrottle.Reset(loginAccountKey(user.Email))
+	_ = MagicLinkThrottle.Reset(magicLinkKey(user.Email))
+
+	if user.IsSuspended() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This account has been suspended"})
+		return
+	}
+	if user.TOTPEnabled {
+		startTwoFactorChallenge(c, &user)
+		return
+	}
+
+	completeLogin(c, &user, "magic_link", "Login successful")
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/mailer"
+	"gitconnect-backend/models"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var magicLinkToken = regexp.MustCompile(`magic-link\?token=([A-Za-z0-9._-]+)`)
+
+func setupMagicLinkTest(t *testing.T) (*gin.Engine, *mailer.MemoryMailer) {
+	t.Helper()
+	setupTestDB(t)
+	previousThrottle, previousMailer := MagicLinkThrottle, mailer.Default
+	MagicLinkThrottle = utils.NewThrottle(utils.NewMemoryAttemptStore(time.Hour), utils.ThrottlePolicy{FreeAttempts: 2, BaseDelay: time.Hour, ResetAfter: 24 * time.Hour})
+	outbox := mailer.NewMemoryMailer()
+	mailer.Default = outbox
+	t.Cleanup(func() { MagicLinkThrottle, mailer.Default = previousThrottle, previousMailer })
+
+	router := gin.New()
+	router.POST("/api/auth/refresh", RefreshToken)
+	router.POST("/api/auth/magic-link", RequestMagicLink)
+	router.POST("/api/auth/magic-link/verify", VerifyMagicLink)
+	return router, outbox
+}
+
+// requestMagicLink asks for a sign-in link for email and returns the token mailed there
+func requestMagicLink(t *testing.T, router *gin.Engine, outbox *mailer.MemoryMailer, email string) string {
+	t.Helper()
+	if status, body := doJSON(t, router, http.MethodPost, "/api/auth/magic-link", MagicLinkRequest{Email: email}); status != http.StatusOK {
+		t.Fatalf("magic-link: status %d, %v", status, body)
+	}
+	message, ok := outbox.Last(email)
+	if !ok {
+		t.Fatalf("no sign-in link sent to %s", email)
+	}
+	match := magicLinkToken.FindStringSubmatch(message.Body)
+	if match == nil {
+		t.Fatalf("no sign-in link in %q", message.Body)
+	}
+	return match[1]
+}
+
+func TestRequestMagicLinkIsRateLimitedPerAddress(t *testing.T) {
+	router, outbox := setupMagicLinkTest(t)
+	user := createTestUser(t, "alice")
+
+	for i := 0; i < 3; i++ {
+		requestMagicLink(t, router, outbox, user.Email)
+	}
+	status, body := doJSON(t, router, http.MethodPost, "/api/auth/magic-link", MagicLinkRequest{Email: user.Email})
+	if status != http.StatusTooManyRequests || body["error"] != tooManyMagicLinks {
+		t.Fatalf("request during backoff: status %d, %v", status, body)
+	}
+	if len(outbox.Messages()) != 3 {
+		t.Fatalf("%d links sent, want 3", len(outbox.Messages()))
+	}
+}
+
+func TestVerifyMagicLinkClaimsUnverifiedAccount(t *testing.T) {
+	router, outbox := setupMagicLinkTest(t)
+
+	// Someone registered the address without owning it and is still signed in
+	hash, _ := bcrypt.GenerateFromPassword([]byte("squatter-password"), bcrypt.MinCost)
+	squatter := models.User{Username: "squatter", Email: "owner@example.com", Password: string(hash), TOTPSecret: "JBSWY3DPEHPK3PXP", TOTPEnabled: true}
+	if err := createAccount(config.DB, &squatter, &models.Profile{}); err != nil {
+		t.Fatal(err)
+	}
+	session := login(t, &squatter)
+
+	token := requestMagicLink(t, router, outbox, squatter.Email)
+	status, body := doJSON(t, router, http.MethodPost, "/api/auth/magic-link/verify", MagicLinkVerifyRequest{Token: token})
+	if status != http.StatusOK || body["token"] == nil {
+		t.Fatalf("verify: status %d, %v", status, body)
+	}
+
+	var user models.User
+	config.DB.First(&user, squatter.ID)
+	if !user.EmailVerified || user.Password != "" || user.TOTPEnabled {
+		t.Fatalf("credentials kept on the claimed account: %+v", user)
+	}
+	if status, _ := refresh(t, router, session.RefreshToken); status != http.StatusUnauthorized {
+		t.Fatalf("squatter's refresh token: status %d, want 401", status)
+	}
+
+	// The link is single-use
+	if status, _ := doJSON(t, router, http.MethodPost, "/api/auth/magic-link/verify", MagicLinkVerifyRequest{Token: token}); status != http.StatusBadRequest {
+		t.Fatalf("reused link: status %d, want 400", status)
+	}
+}
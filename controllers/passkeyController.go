@@ -0,0 +1,351 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitconnect-backend/audit"
+	"gitconnect-backend/config"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/utils"
+	"gitconnect-backend/webauthn"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// How long a passkey ceremony may take, matching the timeout the browser is given
+const passkeyChallengeTTL = 5 * time.Minute
+
+var errChallengeInvalid = errors.New("invalid or expired passkey challenge")
+
+// PasskeyRegistrationRequest completes adding a passkey
+type PasskeyRegistrationRequest struct {
+	Name       string                        `json:"name" binding:"max=100"`
+	Credential webauthn.RegistrationResponse `json:"credential" binding:"required"`
+}
+
+// createPasskeyChallenge starts a ceremony and returns the challenge to put in the options
+func createPasskeyChallenge(purpose string, userID uint) (string, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	// Abandoned ceremonies are cleaned up whenever a new one starts
+	config.DB.Where("expires_at < ?", now).Delete(&models.WebAuthnChallenge{})
+
+	record := models.WebAuthnChallenge{
+		ChallengeHash: utils.HashToken(challenge),
+		Purpose:       purpose,
+		UserID:        userID,
+		ExpiresAt:     now.Add(passkeyChallengeTTL),
+	}
+	if err := config.DB.Create(&record).Error; err != nil {
+		return "", err
+	}
+	return challenge, nil
+}
+
+// consumePasskeyChallenge redeems the challenge a response was signed over. Deleting the row is
+// what makes it single-use, so a captured response cannot be replayed.
+func consumePasskeyChallenge(clientDataJSON, purpose string) (string, *models.WebAuthnChallenge, error) {
+	challenge, err := webauthn.Challenge(clientDataJSON)
+	if err != nil {
+		return "", nil, errChallengeInvalid
+	}
+	challenge = strings.TrimRight(challenge, "=")
+
+	var record models.WebAuthnChallenge
+	result := config.DB.Clauses(clause.Returning{}).
+		Where("challenge_hash = ? AND purpose = ? AND expires_at > ?", utils.HashToken(challenge), purpose, time.Now()).
+		Delete(&record)
+	if result.Error != nil {
+		return "", nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", nil, errChallengeInvalid
+	}
+	return challenge, &record, nil
+}
+
+// userCredentialIDs returns the raw IDs of the user's passkeys
+func userCredentialIDs(userID uint) ([][]byte, error) {
+	var encoded []string
+	if err := config.DB.Model(&models.WebAuthnCredential{}).Where("user_id = ?", userID).
+		Pluck("credential_id", &encoded).Error; err != nil {
+		return nil, err
+	}
+	ids := make([][]byte, 0, len(encoded))
+	for _, id := range encoded {
+		if raw, err := webauthn.DecodeID(id); err == nil {
+			ids = append(ids, raw)
+		}
+	}
+	return ids, nil
+}
+
+// @Summary Start passkey registration
+// @Description Returns options for navigator.credentials.create() to add a passkey to the current account
+// @Tags Passkeys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/passkeys/register/begin [post]
+func BeginPasskeyRegistration(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	exclude, err := userCredentialIDs(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start passkey registration"})
+		return
+	}
+	challenge, err := createPasskeyChallenge(models.WebAuthnRegistration, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start passkey registration"})
+		return
+	}
+
+	// The user handle is stored on the authenticator, so it is the opaque ID rather than the email
+	userHandle := []byte(strconv.FormatUint(uint64(user.ID), 10))
+	c.JSON(http.StatusOK, gin.H{
+		"publicKey": webauthn.Default.CreationOptions(challenge, userHandle, user.Email, user.Username, exclude),
+	})
+}
+
+// @Summary Finish passkey registration
+// @Description Verifies the new credential returned by the browser and saves it
+// @Tags Passkeys
+// @Accept json
+// @Produce json
+// @Param body body PasskeyRegistrationRequest true "Passkey name and credential"
+// @Security BearerAuth
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/passkeys/register/finish [post]
+func FinishPasskeyRegistration(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	var input PasskeyRegistrationRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	challenge, record, err := consumePasskeyChallenge(input.Credential.Response.ClientDataJSON, models.WebAuthnRegistration)
+	if err != nil || record.UserID != user.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired passkey challenge"})
+		return
+	}
+
+	credential, err := webauthn.Default.VerifyRegistration(challenge, &input.Credential)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Passkey could not be verified"})
+		return
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		name = "Passkey"
+	}
+	passkey := models.WebAuthnCredential{
+		UserID:       user.ID,
+		Name:         name,
+		CredentialID: webauthn.EncodeID(credential.ID),
+		PublicKey:    credential.PublicKey,
+		SignCount:    credential.SignCount,
+	}
+
+	var count int64
+	config.DB.Model(&models.WebAuthnCredential{}).Where("credential_id = ?", passkey.CredentialID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This passkey is already registered"})
+		return
+	}
+	if err := config.DB.Create(&passkey).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save passkey"})
+		return
+	}
+
+	audit.Log(c, models.AuditPasskeyAdded, user.ID, audit.Details{"passkey_id": passkey.ID, "name": passkey.Name})
+	c.JSON(http.StatusCreated, gin.H{"message": "Passkey added", "passkey": passkey})
+}
+
+// @Summary List passkeys
+// @Description Lists the passkeys registered to the current account
+// @Tags Passkeys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/passkeys [get]
+func ListPasskeys(c *gin.Context) {
+	userID, exists := middlewares.CurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var passkeys []models.WebAuthnCredential
+	if err := config.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&passkeys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch passkeys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"passkeys": passkeys})
+}
+
+// @Summary Remove a passkey
+// @Description Removes a passkey from the current account
+// @Tags Passkeys
+// @Produce json
+// @Param id path int true "Passkey ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/passkeys/{id} [delete]
+func DeletePasskey(c *gin.Context) {
+	userID, exists := middlewares.CurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid passkey ID"})
+		return
+	}
+
+	result := config.DB.Where("user_id = ?", userID).Delete(&models.WebAuthnCredential{}, id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove passkey"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Passkey not found"})
+		return
+	}
+
+	audit.Log(c, models.AuditPasskeyRemoved, userID, audit.Details{"passkey_id": id})
+	c.JSON(http.StatusOK, gin.H{"message": "Passkey removed"})
+}
+
+// @Summary Start passkey login
+// @Description Returns options for navigator.credentials.get(). The browser offers whichever passkeys the user holds for this site.
+// @Tags Passkeys
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/passkeys/login/begin [post]
+func BeginPasskeyLogin(c *gin.Context) {
+	challenge, err := createPasskeyChallenge(models.WebAuthnLogin, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start passkey login"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": webauthn.Default.RequestOptions(challenge, nil)})
+}
+
+// @Summary Finish passkey login
+// @Description Verifies the passkey assertion and logs in, returning the same tokens as a password login. Accounts with 2FA get a 2FA challenge unless the authenticator verified the user.
+// @Tags Passkeys
+// @Accept json
+// @Produce json
+// @Param body body webauthn.AssertionResponse true "Credential returned by navigator.credentials.get()"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/passkeys/login/finish [post]
+func FinishPasskeyLogin(c *gin.Context) {
+	var input webauthn.AssertionResponse
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Only the IP is throttled: there is no password to guess, and a passkey login must not
+	// be blocked by a password lockout on the same account
+	if !allowAttempt(c, "", loginIPKey(c)) {
+		return
+	}
+
+	challenge, _, err := consumePasskeyChallenge(input.Response.ClientDataJSON, models.WebAuthnLogin)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired passkey challenge"})
+		return
+	}
+
+	var passkey models.WebAuthnCredential
+	if err := config.DB.Where("credential_id = ?", strings.TrimRight(input.ID, "=")).First(&passkey).Error; err != nil {
+		_, _ = IPThrottle.Fail(loginIPKey(c))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unknown passkey"})
+		return
+	}
+
+	assertion, err := webauthn.Default.VerifyAssertion(challenge, &input, &webauthn.Credential{
+		PublicKey: passkey.PublicKey,
+		SignCount: passkey.SignCount,
+	})
+	if err != nil {
+		if errors.Is(err, webauthn.ErrSignCount) {
+			log.Printf("⚠️ Passkey %d of user %d reported a stale signature counter", passkey.ID, passkey.UserID)
+		}
+		_, _ = IPThrottle.Fail(loginIPKey(c))
+		audit.Log(c, models.AuditLoginFailed, passkey.UserID, audit.Details{"method": "passkey", "passkey_id": passkey.ID})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Passkey could not be verified"})
+		return
+	}
+
+	now := time.Now()
+	if err := config.DB.Model(&passkey).Updates(map[string]interface{}{
+		"sign_count":   assertion.SignCount,
+		"last_used_at": now,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, passkey.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unknown passkey"})
+		return
+	}
+
+	// A verified passkey is already two factors (the device and its PIN or biometric);
+	// a presence-only one stands in for the password, so 2FA still applies
+	if user.TOTPEnabled && !assertion.UserVerified {
+		if user.IsSuspended() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This account has been suspended"})
+			return
+		}
+		startTwoFactorChallenge(c, &user)
+		return
+	}
+
+	completeLogin(c, &user, "passkey", "Login successful")
+}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/models"
+	"gitconnect-backend/webauthn"
+	"gitconnect-backend/webauthn/webauthntest"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	passkeyTestRPID   = "gitconnect.test"
+	passkeyTestOrigin = "https://gitconnect.test"
+)
+
+func setupPasskeyTest(t *testing.T, user *models.User) *gin.Engine {
+	t.Helper()
+	previous := webauthn.Default
+	webauthn.Default = &webauthn.RelyingParty{ID: passkeyTestRPID, Name: "GitConnect", Origins: []string{passkeyTestOrigin}}
+	t.Cleanup(func() { webauthn.Default = previous })
+
+	router := gin.New()
+	passkeys := router.Group("/passkeys", authenticateAs(user))
+	passkeys.POST("/register/begin", BeginPasskeyRegistration)
+	passkeys.POST("/register/finish", FinishPasskeyRegistration)
+	router.POST("/login/begin", BeginPasskeyLogin)
+	router.POST("/login/finish", FinishPasskeyLogin)
+	return router
+}
+
+// beginCeremony calls a begin endpoint and returns publicKey.challenge from its options
+func beginCeremony(t *testing.T, router *gin.Engine, path string) string {
+	t.Helper()
+	status, body := doJSON(t, router, http.MethodPost, path, nil)
+	if status != http.StatusOK {
+		t.Fatalf("begin: status %d, %v", status, body)
+	}
+	options, _ := body["publicKey"].(map[string]interface{})
+	challenge, _ := options["challenge"].(string)
+	if challenge == "" {
+		t.Fatalf("begin: no challenge in %v", body)
+	}
+	return challenge
+}
+
+func registerPasskey(t *testing.T, router *gin.Engine, authenticator *webauthntest.Authenticator, name string) {
+	t.Helper()
+	challenge := beginCeremony(t, router, "/passkeys/register/begin")
+	status, body := doJSON(t, router, http.MethodPost, "/passkeys/register/finish", gin.H{
+		"name":       name,
+		"credential": authenticator.Register(challenge),
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("register %s: status %d, %v", name, status, body)
+	}
+}
+
+func loginWithPasskey(t *testing.T, router *gin.Engine, authenticator *webauthntest.Authenticator) (int, map[string]interface{}) {
+	t.Helper()
+	challenge := beginCeremony(t, router, "/login/begin")
+	return doJSON(t, router, http.MethodPost, "/login/finish", authenticator.Assert(challenge))
+}
+
+func TestPasskeysTwoAuthenticatorsOneUser(t *testing.T) {
+	setupTestDB(t)
+	user := createTestUser(t, "octocat")
+	router := setupPasskeyTest(t, user)
+
+	laptop := webauthntest.New(passkeyTestRPID, passkeyTestOrigin)
+	phone := webauthntest.New(passkeyTestRPID, passkeyTestOrigin)
+	registerPasskey(t, router, laptop, "Laptop")
+
+	// The second registration is told not to re-create the first credential
+	status, body := doJSON(t, router, http.MethodPost, "/passkeys/register/begin", nil)
+	options, _ := body["publicKey"].(map[string]interface{})
+	exclude, _ := options["excludeCredentials"].([]interface{})
+	if status != http.StatusOK || len(exclude) != 1 || exclude[0].(map[string]interface{})["id"] != laptop.ID() {
+		t.Fatalf("excludeCredentials = %v", options["excludeCredentials"])
+	}
+	registerPasskey(t, router, phone, "Phone")
+
+	var passkeys []models.WebAuthnCredential
+	config.DB.Where("user_id = ?", user.ID).Order("id").Find(&passkeys)
+	if len(passkeys) != 2 || passkeys[0].Name != "Laptop" || passkeys[1].Name != "Phone" {
+		t.Fatalf("stored passkeys = %+v", passkeys)
+	}
+
+	for _, authenticator := range []*webauthntest.Authenticator{laptop, phone, laptop} {
+		status, body := loginWithPasskey(t, router, authenticator)
+		if status != http.StatusOK {
+			t.Fatalf("login: status %d, %v", status, body)
+		}
+		if who, _ := body["user"].(map[string]interface{}); who["id"] != float64(user.ID) {
+			t.Fatalf("logged in as %v", body["user"])
+		}
+		if body["token"] == nil {
+			t.Fatalf("no access token in %v", body)
+		}
+	}
+
+	// Each passkey keeps its own counter
+	config.DB.Where("user_id = ?", user.ID).Order("id").Find(&passkeys)
+	if passkeys[0].SignCount != 2 || passkeys[1].SignCount != 1 {
+		t.Fatalf("sign counts = %d, %d; want 2, 1", passkeys[0].SignCount, passkeys[1].SignCount)
+	}
+}
+
+func TestPasskeyRegistrationRejectsBadResponses(t *testing.T) {
+	setupTestDB(t)
+	user := createTestUser(t, "octocat")
+	router := setupPasskeyTest(t, user)
+	authenticator := webauthntest.New(passkeyTestRPID, passkeyTestOrigin)
+
+	// A challenge the server never issued
+	challenge, _ := webauthn.NewChallenge()
+	status, _ := doJSON(t, router, http.MethodPost, "/passkeys/register/finish", gin.H{"credential": authenticator.Register(challenge)})
+	if status != http.StatusBadRequest {
+		t.Fatalf("unknown challenge: status %d", status)
+	}
+
+	// An origin the relying party does not allow
+	authenticator.Origin = "https://evil.test"
+	challenge = beginCeremony(t, router, "/passkeys/register/begin")
+	status, _ = doJSON(t, router, http.MethodPost, "/passkeys/register/finish", gin.H{"credential": authenticator.Register(challenge)})
+	if status != http.StatusBadRequest {
+		t.Fatalf("wrong origin: status %d", status)
+	}
+
+	var count int64
+	config.DB.Model(&models.WebAuthnCredential{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("%d passkeys saved from bad responses", count)
+	}
+}
+
+func TestPasskeyLoginRejectsReplayAndClones(t *testing.T) {
+	setupTestDB(t)
+	user := createTestUser(t, "octocat")
+	router := setupPasskeyTest(t, user)
+	authenticator := webauthntest.New(passkeyTestRPID, passkeyTestOrigin)
+	registerPasskey(t, router, authenticator, "Laptop")
+
+	challenge := beginCeremony(t, router, "/login/begin")
+	response := authenticator.Assert(challenge)
+	if status, body := doJSON(t, router, http.MethodPost, "/login/finish", response); status != http.StatusOK {
+		t.Fatalf("login: status %d, %v", status, body)
+	}
+
+	// The challenge is single-use
+	if status, _ := doJSON(t, router, http.MethodPost, "/login/finish", response); status != http.StatusBadRequest {
+		t.Fatalf("replayed assertion: status %d", status)
+	}
+
+	// A copy of the authenticator still at the old counter
+	authenticator.SignCount = 0
+	if status, _ := loginWithPasskey(t, router, authenticator); status != http.StatusUnauthorized {
+		t.Fatalf("stale counter: status %d", status)
+	}
+
+	// A wrong origin fails the ceremony and leaves the counter alone
+	authenticator.SignCount = 5
+	authenticator.Origin = "https://evil.test"
+	if status, _ := loginWithPasskey(t, router, authenticator); status != http.StatusUnauthorized {
+		t.Fatalf("wrong origin: status %d", status)
+	}
+	var passkey models.WebAuthnCredential
+	config.DB.Where("user_id = ?", user.ID).First(&passkey)
+	if passkey.SignCount != 1 {
+		t.Fatalf("sign count = %d after failed logins, want 1", passkey.SignCount)
+	}
+}
@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gitconnect-backend/audit"
+	"gitconnect-backend/config"
+	"gitconnect-backend/mailer"
+	"gitconnect-backend/models"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	passwordResetTTL       = time.Hour
+	defaultFrontendBaseURL = "https://gitconnect-frontend.vercel.app"
+
+	// At most this many reset emails go to one account per passwordResetTTL
+	passwordResetEmailLimit = 3
+)
+
+var errResetTokenInvalid = errors.New("invalid reset token")
+
+// ForgotPasswordRequest starts a password reset
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest completes a password reset
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+// frontendURL builds a link to a page of the web app, using FRONTEND_URL when set
+func frontendURL(path string, query url.Values) string {
+	base := strings.TrimRight(os.Getenv("FRONTEND_URL"), "/")
+	if base == "" {
+		base = defaultFrontendBaseURL
+	}
+	link := base + path
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+	return link
+}
+
+// @Summary Request a password reset
+// @Description Emails a single-use reset link if the address belongs to an account. Always responds the same way so emails cannot be enumerated; an account that already got several links within the hour is sent no more until it has passed.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body ForgotPasswordRequest true "Account email"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /api/auth/forgot-password [post]
+func ForgotPassword(c *gin.Context) {
+	var input ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"message": "If an account exists for that email, a reset link has been sent"}
+
+	var user models.User
+	if err := config.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	// Quietly stop mailing an account that keeps asking, so the endpoint can't be used to flood an inbox
+	var recent int64
+	if err := config.DB.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND created_at > ?", user.ID, time.Now().Add(-passwordResetTTL)).
+		Count(&recent).Error; err != nil {
+		log.Println("❌ Failed to count recent password resets:", err)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	if recent >= passwordResetEmailLimit {
+		log.Printf("⚠️ Password reset email limit reached for user %d", user.ID)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	if err := sendPasswordResetEmail(&user); err != nil {
+		log.Println("❌ Failed to send password reset email:", err)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// sendPasswordResetEmail replaces any outstanding reset token with a new one and mails it
+func sendPasswordResetEmail(user *models.User) error {
+	raw, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Only the most recent link works
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: utils.HashToken(raw),
+			ExpiresAt: now.Add(passwordResetTTL),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	link := frontendURL("/reset-password", url.Values{"token": {raw}})
+	return mailer.Default.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your GitConnect password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password for your GitConnect account. "+
+			"Open the link below to choose a new one:\n\n%s\n\n"+
+			"The link expires in %d minutes and can only be used once. If you did not ask for this, you can ignore this email.\n",
+			user.Username, link, int(passwordResetTTL.Minutes())),
+	})
+}
+
+// @Summary Reset password
+// @Description Sets a new password using a reset token, signs the user out of every session and revokes their personal access tokens. Resetting the password of an account whose email was never verified verifies it and removes any 2FA and passkeys its registrant set up, since the link proves control of the mailbox.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/reset-password [post]
+func ResetPassword(c *gin.Context) {
+	var input ResetPasswordRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	var userID uint
+	var email string
+	claimed := false
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var token models.PasswordResetToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", utils.HashToken(input.Token)).
+			First(&token).Error; err != nil {
+			return errResetTokenInvalid
+		}
+
+		now := time.Now()
+		if !token.IsActive(now) {
+			return errResetTokenInvalid
+		}
+
+		var user models.User
+		if err := tx.First(&user, token.UserID).Error; err != nil {
+			return err
+		}
+		// Whoever registered an unverified address may not own it; the mailbox owner takes the account over
+		if !user.EmailVerified {
+			claimed = true
+			if err := claimUnverifiedAccount(tx, &user); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+			return err
+		}
+		if err := invalidateEmailChanges(tx, user.ID); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		userID, email = user.ID, user.Email
+		return nil
+	})
+	if errors.Is(err, errResetTokenInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	// Whoever knew the old password must not stay logged in, nor keep tokens they created
+	if err := revokeAllUserTokens(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password changed but failed to sign out existing sessions"})
+		return
+	}
+	if err := revokePersonalAccessTokens(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password changed but failed to sign out existing sessions"})
+		return
+	}
+
+	if claimed {
+		audit.Log(c, models.AuditEmailVerified, userID, audit.Details{"email": email, "method": "password_reset", "credentials_reset": true})
+	}
+	audit.Log(c, models.AuditPasswordReset, userID, nil)
+
+	// Proving control of the mailbox is as good as the unlock link
+	_ = AccountThrottle.Reset(loginAccountKey(email))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset. Please log in with your new password."})
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/mailer"
+	"gitconnect-backend/models"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var resetLinkToken = regexp.MustCompile(`reset-password\?token=([A-Za-z0-9_-]+)`)
+
+func setupPasswordResetTest(t *testing.T) (*gin.Engine, *mailer.MemoryMailer) {
+	t.Helper()
+	setupTestDB(t)
+	outbox := mailer.NewMemoryMailer()
+	previous := mailer.Default
+	mailer.Default = outbox
+	t.Cleanup(func() { mailer.Default = previous })
+
+	router := gin.New()
+	router.POST("/api/auth/refresh", RefreshToken)
+	router.POST("/api/auth/forgot-password", ForgotPassword)
+	router.POST("/api/auth/reset-password", ResetPassword)
+	return router, outbox
+}
+
+// requestReset asks for a reset link for email and returns the token it mailed, if any
+func requestReset(t *testing.T, router *gin.Engine, outbox *mailer.MemoryMailer, email string) string {
+	t.Helper()
+	sent := len(outbox.Messages())
+	if status, body := doJSON(t, router, http.MethodPost, "/api/auth/forgot-password", ForgotPasswordRequest{Email: email}); status != http.StatusOK {
+		t.Fatalf("forgot-password: status %d, %v", status, body)
+	}
+	messages := outbox.Messages()
+	if len(messages) == sent {
+		return ""
+	}
+	match := resetLinkToken.FindStringSubmatch(messages[len(messages)-1].Body)
+	if match == nil {
+		t.Fatalf("no reset link in %q", messages[len(messages)-1].Body)
+	}
+	return match[1]
+}
+
+func TestForgotPasswordLimitsEmailsPerAccount(t *testing.T) {
+	router, outbox := setupPasswordResetTest(t)
+	user := createTestUser(t, "alice")
+
+	var tokens []string
+	for i := 0; i < passwordResetEmailLimit+2; i++ {
+		if token := requestReset(t, router, outbox, user.Email); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	if len(tokens) != passwordResetEmailLimit {
+		t.Fatalf("%d reset emails sent, want %d", len(tokens), passwordResetEmailLimit)
+	}
+	if token := requestReset(t, router, outbox, "nobody@example.com"); token != "" {
+		t.Fatal("reset email sent to an unknown address")
+	}
+
+	// Only the newest link works
+	if status, _ := doJSON(t, router, http.MethodPost, "/api/auth/reset-password", ResetPasswordRequest{Token: tokens[0], Password: "new-password"}); status != http.StatusBadRequest {
+		t.Fatalf("superseded link: status %d, want 400", status)
+	}
+	if status, _ := doJSON(t, router, http.MethodPost, "/api/auth/reset-password", ResetPasswordRequest{Token: tokens[len(tokens)-1], Password: "new-password"}); status != http.StatusOK {
+		t.Fatalf("newest link: status %d, want 200", status)
+	}
+}
+
+func TestResetPasswordClaimsUnverifiedAccount(t *testing.T) {
+	router, outbox := setupPasswordResetTest(t)
+
+	// Someone registered the address without owning it and is still signed in
+	hash, _ := bcrypt.GenerateFromPassword([]byte("squatter-password"), bcrypt.MinCost)
+	squatter := models.User{Username: "squatter", Email: "owner@example.com", Password: string(hash), TOTPSecret: "JBSWY3DPEHPK3PXP", TOTPEnabled: true}
+	if err := config.DB.Create(&squatter).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := replaceRecoveryCodes(config.DB, squatter.ID); err != nil {
+		t.Fatal(err)
+	}
+	session := login(t, &squatter)
+
+	token := requestReset(t, router, outbox, squatter.Email)
+	if status, body := doJSON(t, router, http.MethodPost, "/api/auth/reset-password", ResetPasswordRequest{Token: token, Password: "owner-password"}); status != http.StatusOK {
+		t.Fatalf("reset: status %d, %v", status, body)
+	}
+
+	var user models.User
+	config.DB.First(&user, squatter.ID)
+	if !user.EmailVerified || user.EmailVerifiedAt == nil {
+		t.Fatal("reset through the mailbox did not verify the email")
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("owner-password")) != nil {
+		t.Fatal("new password not set")
+	}
+	var codes int64
+	config.DB.Model(&models.RecoveryCode{}).Where("user_id = ?", user.ID).Count(&codes)
+	if user.TOTPEnabled || user.TOTPSecret != "" || codes != 0 {
+		t.Fatalf("squatter's 2FA survived the reset: enabled %v, %d recovery codes", user.TOTPEnabled, codes)
+	}
+	if status, _ := refresh(t, router, session.RefreshToken); status != http.StatusUnauthorized {
+		t.Fatalf("squatter's refresh token: status %d, want 401", status)
+	}
+
+	// The link is single-use
+	if status, _ := doJSON(t, router, http.MethodPost, "/api/auth/reset-password", ResetPasswordRequest{Token: token, Password: "another-password"}); status != http.StatusBadRequest {
+		t.Fatalf("reused link: status %d, want 400", status)
+	}
+}
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitconnect-backend/audit"
+	"gitconnect-backend/config"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// CreatePersonalAccessTokenRequest describes a new token
+type CreatePersonalAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"min=0,max=365"` // 0 means the token never expires
+}
+
+func personalAccessTokenJSON(token *models.PersonalAccessToken) gin.H {
+	return gin.H{
+		"id":           token.ID,
+		"name":         token.Name,
+		"hint":         token.Hint,
+		"scopes":       token.ScopeList(),
+		"expires_at":   token.ExpiresAt,
+		"last_used_at": token.LastUsedAt,
+		"revoked_at":   token.RevokedAt,
+		"created_at":   token.CreatedAt,
+	}
+}
+
+// @Summary Create a personal access token
+// @Description Creates a scoped token for scripts and bots. The token value is returned only in this response.
+// @Tags Personal Access Tokens
+// @Accept json
+// @Produce json
+// @Param body body CreatePersonalAccessTokenRequest true "Token name, scopes and lifetime"
+// @Security BearerAuth
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/tokens [post]
+func CreatePersonalAccessToken(c *gin.Context) {
+	userID, exists := middlewares.CurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input CreatePersonalAccessTokenRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// De-duplicate and validate scopes
+	seen := map[string]bool{}
+	var scopes []string
+	for _, scope := range input.Scopes {
+		if !models.ValidScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope: " + scope, "valid_scopes": models.AllScopes})
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	secret, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+	raw := models.PersonalAccessTokenPrefix + secret
+
+	token := models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      strings.TrimSpace(input.Name),
+		TokenHash: utils.HashToken(raw),
+		Hint:      raw[len(raw)-4:],
+		Scopes:    strings.Join(scopes, " "),
+	}
+	if input.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, input.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := config.DB.Create(&token).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	audit.Log(c, models.AuditTokenCreated, token.UserID, audit.Details{"token_id": token.ID, "name": token.Name, "scopes": scopes})
+
+	response := personalAccessTokenJSON(&token)
+	response["token"] = raw
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Token created. Copy it now; it will not be shown again.",
+		"token":   response,
+	})
+}
+
+// @Summary List personal access tokens
+// @Description Lists the current user's tokens without their values
+// @Tags Personal Access Tokens
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/tokens [get]
+func ListPersonalAccessTokens(c *gin.Context) {
+	userID, exists := middlewares.CurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var tokens []models.PersonalAccessToken
+	if err := config.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tokens"})
+		return
+	}
+
+	list := make([]gin.H, len(tokens))
+	for i := range tokens {
+		list[i] = personalAccessTokenJSON(&tokens[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"tokens": list})
+}
+
+// @Summary Revoke a personal access token
+// @Description Immediately stops the token from authenticating
+// @Tags Personal Access Tokens
+// @Produce json
+// @Param id path int true "Token ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/tokens/{id} [delete]
+func RevokePersonalAccessToken(c *gin.Context) {
+	userID, exists := middlewares.CurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	// Scoped to the caller, so other users' tokens look like they do not exist
+	var token models.PersonalAccessToken
+	if err := config.DB.Where("user_id = ?", userID).First(&token, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+
+	if token.RevokedAt == nil {
+		if err := config.DB.Model(&token).Update("revoked_at", time.Now()).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+			return
+		}
+		audit.Log(c, models.AuditTokenRevoked, token.UserID, audit.Details{"token_id": token.ID, "name": token.Name})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked"})
+}
+
+// revokePersonalAccessTokens revokes all of a user's personal access tokens, e.g. after a password reset
+func revokePersonalAccessTokens(userID uint) error {
+	return config.DB.Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
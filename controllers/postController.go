@@ -1,20 +1,36 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"slices"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"gitconnect-backend/config"
+	"gitconnect-backend/middlewares"
 	"gitconnect-backend/models"
+	"gitconnect-backend/policy"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// PostRequest is the part of a post its author writes; counters and ownership are set by the server
+type PostRequest struct {
+	Content string `json:"content" binding:"required,max=5000"`
+}
+
+// CommentRequest is the part of a comment its author writes
+type CommentRequest struct {
+	Content string `json:"content" binding:"required,max=2000"`
+}
+
 // @Summary Create a new post
 // @Description Allows an authenticated user to create a new post
 // @Tags Posts
 // @Accept json
 // @Produce json
-// @Param post body models.Post true "Post Data"
+// @Param post body PostRequest true "Post Data"
 // @Security BearerAuth
 // @Success 201 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
@@ -22,36 +38,43 @@ import (
 // @Failure 500 {object} map[string]string
 // @Router /api/posts [post]
 func CreatePost(c *gin.Context) {
-	// Get user ID from token
-	userID, exists := c.Get("user_id")
+	// Get the author from the token
+	user, exists := middlewares.CurrentUser(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	var post models.Post
-	if err := c.ShouldBindJSON(&post); err != nil {
+	var input PostRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Assign the authenticated user to the post
-	post.UserID = userID.(uint)
+	post := models.Post{Content: input.Content, UserID: user.ID}
 
 	// Save post
 	if err := config.DB.Create(&post).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create post"})
 		return
 	}
+	post.User = *user
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Post created", "post": post})
+	views, err := postViews(c, []models.Post{post})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create post"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Post created", "post": views[0]})
 }
 
 // @Summary Get all posts
-// @Description Fetch all posts with user details
+// @Description Fetch all posts with user details. With a bearer token each post also carries a viewer object: the caller's reaction and whether they may edit or delete it. Posts by users the caller blocked, or who blocked the caller, are left out.
 // @Tags Posts
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Success 200 {object} map[string]interface{}
 // @Failure 500 {object} map[string]string
 // @Router /api/posts [get]
@@ -59,20 +82,39 @@ func GetPosts(c *gin.Context) {
 	var posts []models.Post
 
 	// Include user details in the response
-	if err := config.DB.Preload("User").Find(&posts).Error; err != nil {
+	query := preloadPostMedia(config.DB.Preload("User"))
+
+	// Leave out users the caller blocked or was blocked by
+	hidden, err := hiddenUserIDs(policy.ActorFromContext(c).UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+	if len(hidden) > 0 {
+		query = query.Where("user_id NOT IN ?", hidden)
+	}
+
+	if err := query.Find(&posts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+
+	views, err := postViews(c, posts)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"posts": posts})
+	c.JSON(http.StatusOK, gin.H{"posts": views})
 }
 
 // @Summary Get a single post
-// @Description Fetch a post by ID
+// @Description Fetch a post by ID. With a bearer token the post also carries a viewer object. A post by a user the caller blocked, or who blocked the caller, is not found.
 // @Tags Posts
 // @Accept json
 // @Produce json
 // @Param id path int true "Post ID"
+// @Security BearerAuth
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
@@ -88,16 +130,32 @@ func GetPost(c *gin.Context) {
 	}
 
 	// Find post
-	if err := config.DB.Preload("User").First(&post, id).Error; err != nil {
+	if err := preloadPostMedia(config.DB.Preload("User")).First(&post, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	// A post hidden by a block is not found, as for anyone else who cannot see it
+	blocked, err := blockedBetween(config.DB, policy.ActorFromContext(c).UserID, post.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	if blocked {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"post": post})
+	views, err := postViews(c, []models.Post{post})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"post": views[0]})
 }
 
 // @Summary Delete a post
-// @Description Deletes a post (Only the author can delete)
+// @Description Deletes a post (only the author, or a moderator)
 // @Tags Posts
 // @Accept json
 // @Produce json
@@ -111,13 +169,6 @@ func GetPost(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /api/posts/{id} [delete]
 func DeletePost(c *gin.Context) {
-	// Get user ID from token
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
-
 	var post models.Post
 
 	// Convert ID param to uint
@@ -133,31 +184,33 @@ func DeletePost(c *gin.Context) {
 		return
 	}
 
-	// Check if the logged-in user is the post owner
-	if post.UserID != userID.(uint) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete your own post"})
+	// Only the author (or a moderator) may delete the post
+	if !policy.Authorize(c, policy.ActionDelete, &post) {
 		return
 	}
 
-	// Delete post
+	// Delete post; its images go with it
+	media := postMediaKeys(config.DB, []uint{post.ID})
 	if err := config.DB.Delete(&post).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete post"})
 		return
 	}
+	deletePostMedia(c.Request.Context(), media)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Post deleted"})
 }
 
 // @Summary Update a post
-// @Description Updates an existing post
+// @Description Updates an existing post (only the author, or a moderator)
 // @Tags Posts
 // @Accept json
 // @Produce json
 // @Param id path int true "Post ID"
-// @Param post body models.Post true "Updated Post Data"
+// @Param post body PostRequest true "Updated Post Data"
 // @Security BearerAuth
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/posts/{id} [put]
@@ -172,24 +225,39 @@ func UpdatePost(c *gin.Context) {
 	}
 
 	// Find post
-	if err := config.DB.First(&post, id).Error; err != nil {
+	if err := preloadPostMedia(config.DB.Preload("User")).First(&post, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 		return
 	}
 
-	// Bind request data to the post object
-	if err := c.ShouldBindJSON(&post); err != nil {
+	// Only the author (or a moderator) may edit the post
+	if !policy.Authorize(c, policy.ActionUpdate, &post) {
+		return
+	}
+
+	// Only the content is editable; identity, ownership and counters stay as they are
+	var input PostRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Save updated post
-	config.DB.Save(&post)
-	c.JSON(http.StatusOK, gin.H{"message": "Post updated", "post": post})
+	if err := config.DB.Model(&post).Update("content", input.Content).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post"})
+		return
+	}
+
+	views, err := postViews(c, []models.Post{post})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Post updated", "post": views[0]})
 }
 
 // @Summary Like a post
-// @Description Allows a user to like a post
+// @Description Likes a post. Each user counts once: liking again changes nothing, and a dislike is switched to a like.
 // @Tags Posts
 // @Accept json
 // @Produce json
@@ -201,33 +269,16 @@ func UpdatePost(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /api/posts/{id}/like [post]
 func LikePost(c *gin.Context) {
-	var post models.Post
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+	post, ok := reactToPost(c, models.ReactionLike)
+	if !ok {
 		return
 	}
 
-	// Find the post
-	if err := config.DB.First(&post, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
-		return
-	}
-
-	// Increment the like counter
-	post.LikePost()
-
-	// Save post with updated likes
-	if err := config.DB.Save(&post).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to like post"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Post liked", "likes": post.Likes})
+	c.JSON(http.StatusOK, gin.H{"message": "Post liked", "likes": post.Likes, "dislikes": post.Dislikes, "reaction": models.ReactionLike})
 }
 
 // @Summary Dislike a post
-// @Description Allows a user to dislike a post
+// @Description Dislikes a post. Each user counts once: disliking again changes nothing, and a like is switched to a dislike.
 // @Tags Posts
 // @Accept json
 // @Produce json
@@ -239,29 +290,72 @@ func LikePost(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /api/posts/{id}/dislike [post]
 func DislikePost(c *gin.Context) {
-	var post models.Post
+	post, ok := reactToPost(c, models.ReactionDislike)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post disliked", "likes": post.Likes, "dislikes": post.Dislikes, "reaction": models.ReactionDislike})
+}
+
+// reactToPost records the caller's like or dislike on post :id, moving an opposite reaction
+// rather than adding a second one; it writes the error response itself
+func reactToPost(c *gin.Context, kind string) (*models.Post, bool) {
+	userID, exists := middlewares.CurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
-		return
+		return nil, false
 	}
 
-	// Find the post
-	if err := config.DB.First(&post, id).Error; err != nil {
+	var post models.Post
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Locking the post serialises reactions to it, so the counters stay in step with the rows
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&post, id).Error; err != nil {
+			return err
+		}
+		// A post hidden by a block is not found
+		if blocked, err := blockedBetween(tx, userID, post.UserID); err != nil {
+			return err
+		} else if blocked {
+			return gorm.ErrRecordNotFound
+		}
+
+		var reaction models.PostReaction
+		err := tx.Where("post_id = ? AND user_id = ?", post.ID, userID).First(&reaction).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			reaction = models.PostReaction{PostID: post.ID, UserID: userID, Kind: kind}
+			if err := tx.Create(&reaction).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case reaction.Kind == kind:
+			return nil
+		default:
+			post.RemoveReaction(reaction.Kind)
+			if err := tx.Model(&reaction).Update("kind", kind).Error; err != nil {
+				return err
+			}
+		}
+
+		post.AddReaction(kind)
+		return tx.Model(&post).Updates(map[string]interface{}{"likes": post.Likes, "dislikes": post.Dislikes}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
-		return
+		return nil, false
 	}
-
-	// Increment the dislike counter
-	post.DislikePost()
-
-	// Save post with updated dislikes
-	if err := config.DB.Save(&post).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dislike post"})
-		return
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + kind + " post"})
+		return nil, false
 	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Post disliked", "dislikes": post.Dislikes})
+	return &post, true
 }
 
 // @Summary Comment on a post
@@ -270,7 +364,7 @@ func DislikePost(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Post ID"
-// @Param comment body models.Comment true "Comment Data"
+// @Param comment body CommentRequest true "Comment Data"
 // @Security BearerAuth
 // @Success 201 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
@@ -278,8 +372,8 @@ func DislikePost(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /api/posts/{id}/comments [post]
 func CommentOnPost(c *gin.Context) {
-	// Get user ID from token
-	userID, exists := c.Get("user_id")
+	// Get the author from the token
+	user, exists := middlewares.CurrentUser(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -292,57 +386,178 @@ func CommentOnPost(c *gin.Context) {
 		return
 	}
 
-	var comment models.Comment
-	// Bind comment data to the model
-	if err := c.ShouldBindJSON(&comment); err != nil {
+	var input CommentRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	var post models.Post
+	if err := config.DB.Select("id", "user_id").First(&post, postID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	blocked, err := blockedBetween(config.DB, user.ID, post.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post comment"})
+		return
+	}
+	if blocked {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
 	// Assign the post ID and user ID
-	comment.PostID = uint(postID)
-	comment.UserID = userID.(uint)
+	comment := models.Comment{PostID: post.ID, UserID: user.ID, Content: input.Content}
 
 	// Save the comment
 	if err := config.DB.Create(&comment).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post comment"})
 		return
 	}
+	comment.User = *user
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Comment added", "comment": comment})
+	c.JSON(http.StatusCreated, gin.H{"message": "Comment added", "comment": commentViews(c, []models.Comment{comment})[0]})
 }
 
 // @Summary Get all comments for a post
-// @Description Fetch all comments for a specific post
+// @Description Fetch all comments for a specific post. With a bearer token each comment also carries a viewer object. Comments by users the caller blocked, or who blocked the caller, are left out, and a post hidden by a block is not found.
 // @Tags Posts
 // @Accept json
 // @Produce json
 // @Param id path int true "Post ID"
+// @Security BearerAuth
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/posts/{id}/comments [get]
 func GetCommentsForPost(c *gin.Context) {
-    postID, err := strconv.Atoi(c.Param("id"))
-    if err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
-        return
-    }
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	// The comments of a post hidden by a block are hidden with it
+	actorID := policy.ActorFromContext(c).UserID
+	var post models.Post
+	if err := config.DB.Select("id", "user_id").First(&post, postID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	hidden, err := hiddenUserIDs(actorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
+	if slices.Contains(hidden, post.UserID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
+	var comments []models.Comment
+	query := config.DB.Where("post_id = ?", post.ID).Preload("User")
+	if len(hidden) > 0 {
+		query = query.Where("user_id NOT IN ?", hidden)
+	}
+	if err := query.Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"comments": commentViews(c, comments)})
+}
+
+// @Summary Update a comment
+// @Description Edits a comment (only its author, or a moderator)
+// @Tags Posts
+// @Accept json
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param commentId path int true "Comment ID"
+// @Param comment body CommentRequest true "Updated Comment Data"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/posts/{id}/comments/{commentId} [put]
+func UpdateComment(c *gin.Context) {
+	comment, ok := findComment(c)
+	if !ok {
+		return
+	}
 
-    var comments []models.Comment
+	// Only the author (or a moderator) may edit the comment
+	if !policy.Authorize(c, policy.ActionUpdate, comment) {
+		return
+	}
 
-    // Fetch comments with Post and User details only if necessary
-    query := config.DB.Where("post_id = ?", postID)
+	var input CommentRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-    // Remove Preload if it's causing issues
-    query = query.Preload("User") // If User exists, keep this
+	if err := config.DB.Model(comment).Update("content", input.Content).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+		return
+	}
 
-    if err := query.Find(&comments).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
-        return
-    }
+	c.JSON(http.StatusOK, gin.H{"message": "Comment updated", "comment": commentViews(c, []models.Comment{*comment})[0]})
+}
 
-    c.JSON(http.StatusOK, gin.H{"comments": comments})
+// @Summary Delete a comment
+// @Description Deletes a comment (only its author, or a moderator)
+// @Tags Posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param commentId path int true "Comment ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/posts/{id}/comments/{commentId} [delete]
+func DeleteComment(c *gin.Context) {
+	comment, ok := findComment(c)
+	if !ok {
+		return
+	}
+
+	// Only the author (or a moderator) may delete the comment
+	if !policy.Authorize(c, policy.ActionDelete, comment) {
+		return
+	}
+
+	if err := config.DB.Delete(comment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted"})
 }
 
+// findComment loads the comment named by :commentId under post :id; it writes the error response itself
+func findComment(c *gin.Context) (*models.Comment, bool) {
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return nil, false
+	}
+	commentID, err := strconv.Atoi(c.Param("commentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return nil, false
+	}
+
+	var comment models.Comment
+	if err := config.DB.Preload("User").Where("post_id = ?", postID).First(&comment, commentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return nil, false
+	}
+	return &comment, true
+}
@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/imaging"
+	"gitconnect-backend/models"
+	"gitconnect-backend/policy"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Largest accepted post image, overridable with POST_MEDIA_MAX_BYTES
+const defaultPostMediaMaxBytes = 10 << 20
+
+var errTooManyMedia = errors.New("post has the maximum number of images")
+
+func postMediaMaxBytes() int64 {
+	if value := os.Getenv("POST_MEDIA_MAX_BYTES"); value != "" {
+		if parsed, err := strconv.ParseInt(value, 10, 64); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return defaultPostMediaMaxBytes
+}
+
+// preloadPostMedia loads the images of posts in the order they were added
+func preloadPostMedia(db *gorm.DB) *gorm.DB {
+	return db.Preload("Media", func(db *gorm.DB) *gorm.DB { return db.Order("id") })
+}
+
+// postMediaKeys returns the blob keys of the images on the given posts: a slice of IDs or a subquery
+func postMediaKeys(db *gorm.DB, postIDs interface{}) []string {
+	var keys []string
+	if err := db.Model(&models.PostMedia{}).Where("post_id IN (?)", postIDs).Pluck("key", &keys).Error; err != nil {
+		log.Println("❌ Failed to look up post images:", err)
+	}
+	return keys
+}
+
+// userPostMediaKeys returns the blob keys of the images on a user's posts, so they can be deleted
+// with the account. Read them in the deleting transaction once the user row is locked.
+func userPostMediaKeys(tx *gorm.DB, userID uint) []string {
+	return postMediaKeys(tx, tx.Model(&models.Post{}).Select("id").Where("user_id = ?", userID))
+}
+
+// deletePostMedia removes post images and their previews once their rows are gone
+func deletePostMedia(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		deleteImage(ctx, key, models.PostMediaSizes)
+	}
+}
+
+// findPostMedia loads image :mediaId of post :id; it writes the error response itself
+func findPostMedia(c *gin.Context) (*models.PostMedia, bool) {
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return nil, false
+	}
+	mediaID, err := strconv.Atoi(c.Param("mediaId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID"})
+		return nil, false
+	}
+
+	var media models.PostMedia
+	if err := config.DB.Preload("Post").Where("post_id = ?", postID).First(&media, mediaID).Error; err != nil || media.Post == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return nil, false
+	}
+	return &media, true
+}
+
+// @Summary Add an image to a post
+// @Description Attaches an image to a post (only its author, or a moderator), up to 4 per post. Expects a multipart form with the field "image": a JPEG, PNG, GIF or WebP image of at most 10 MB by default. The image is decoded and re-encoded, which strips EXIF and GPS metadata, and previews fitting 256, 512 and 1024 px squares are generated; urls lists where each is served.
+// @Tags Posts
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param image formData file true "Image"
+// @Security BearerAuth
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/posts/{id}/media [post]
+func UploadPostMedia(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+	var post models.Post
+	if err := config.DB.First(&post, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if !policy.Authorize(c, policy.ActionUpdate, &post) {
+		return
+	}
+
+	data, ok := readUploadedImage(c, postMediaMaxBytes())
+	if !ok {
+		return
+	}
+	processed, ok := processUploadedImage(c, data, models.PostMediaSizes, imaging.ProcessPreviews)
+	if !ok {
+		return
+	}
+
+	key, err := storeImage(c.Request.Context(), fmt.Sprintf("post-media/%d", post.ID), processed, models.PostMediaSizes)
+	if err != nil {
+		log.Println("❌ Failed to store post image:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
+		return
+	}
+
+	media := models.PostMedia{
+		PostID:      post.ID,
+		Key:         key,
+		ContentType: processed.Original.ContentType,
+		Width:       processed.Original.Width,
+		Height:      processed.Original.Height,
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Locking the post keeps concurrent uploads from passing the limit together
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Post{}, post.ID).Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.PostMedia{}).Where("post_id = ?", post.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= models.MaxPostMedia {
+			return errTooManyMedia
+		}
+		return tx.Create(&media).Error
+	})
+	if err != nil {
+		deleteImage(c.Request.Context(), key, models.PostMediaSizes)
+	}
+	if errors.Is(err, errTooManyMedia) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("A post can have at most %d images", models.MaxPostMedia)})
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Image added", "media": media})
+}
+
+// @Summary Get a post image
+// @Description Serves an image attached to a post, or one of its previews when size is given. Images on a post hidden by a block are not found.
+// @Tags Posts
+// @Produce image/jpeg,image/png
+// @Param id path int true "Post ID"
+// @Param mediaId path int true "Image ID"
+// @Param size query int false "Preview size in pixels: 256, 512 or 1024"
+// @Security BearerAuth
+// @Success 200 {file} file "The image"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/posts/{id}/media/{mediaId} [get]
+func GetPostMedia(c *gin.Context) {
+	media, ok := findPostMedia(c)
+	if !ok {
+		return
+	}
+	blocked, err := blockedBetween(config.DB, policy.ActorFromContext(c).UserID, media.Post.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load image"})
+		return
+	}
+	if blocked {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+
+	serveImage(c, media.Key, models.PostMediaSizes, "Image not found")
+}
+
+// @Summary Remove an image from a post
+// @Description Deletes an image attached to a post (only its author, or a moderator)
+// @Tags Posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param mediaId path int true "Image ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/posts/{id}/media/{mediaId} [delete]
+func DeletePostMedia(c *gin.Context) {
+	media, ok := findPostMedia(c)
+	if !ok {
+		return
+	}
+	if !policy.Authorize(c, policy.ActionUpdate, media.Post) {
+		return
+	}
+
+	if err := config.DB.Delete(media).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove image"})
+		return
+	}
+	deleteImage(c.Request.Context(), media.Key, models.PostMediaSizes)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Image removed"})
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"log"
+	"time"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/models"
+	"gitconnect-backend/policy"
+	"github.com/gin-gonic/gin"
+)
+
+// Author is how a post or comment author is shown publicly; their email and account state stay private
+type Author struct {
+	ID               uint              `json:"id"`
+	Username         string            `json:"username"`
+	AvatarURLs       map[string]string `json:"avatar_urls,omitempty"` // Profile picture variants, as in profile_picture_urls
+	Github           string            `json:"github,omitempty"`
+	GithubVerified   bool              `json:"github_verified"`
+	GithubVerifiedAt *time.Time        `json:"github_verified_at,omitempty"`
+}
+
+func authorOf(user *models.User, profiles map[uint]models.Profile) Author {
+	author := Author{ID: user.ID, Username: user.Username}
+	if profile, ok := profiles[user.ID]; ok {
+		author.AvatarURLs = profile.ProfilePictureURLs
+		author.Github = profile.Github
+		author.GithubVerified = profile.GithubVerified
+		author.GithubVerifiedAt = profile.GithubVerifiedAt
+	}
+	return author
+}
+
+// authorProfiles loads what authors show of their profiles in one query. It is cosmetic, so a
+// failure is logged and the authors are shown without it.
+func authorProfiles(userIDs []uint) map[uint]models.Profile {
+	byUser := make(map[uint]models.Profile)
+	if len(userIDs) == 0 {
+		return byUser
+	}
+	var profiles []models.Profile
+	if err := config.DB.Select("id", "user_id", "profile_picture", "github", "github_verified", "github_verified_at").
+		Where("user_id IN ?", userIDs).Find(&profiles).Error; err != nil {
+		log.Println("❌ Failed to load author profiles:", err)
+		return byUser
+	}
+	for _, profile := range profiles {
+		byUser[profile.UserID] = profile
+	}
+	return byUser
+}
+
+// PostViewer describes the caller's own relation to a post
+type PostViewer struct {
+	Reaction  *string `json:"reaction"` // "like", "dislike" or null
+	IsAuthor  bool    `json:"is_author"`
+	CanEdit   bool    `json:"can_edit"`
+	CanDelete bool    `json:"can_delete"`
+}
+
+// PostView is a post as returned to one caller; Viewer is left out for anonymous requests
+type PostView struct {
+	models.Post
+	User   Author      `json:"user"`
+	Viewer *PostViewer `json:"viewer,omitempty"`
+}
+
+// CommentViewer describes the caller's own relation to a comment
+type CommentViewer struct {
+	IsAuthor  bool `json:"is_author"`
+	CanEdit   bool `json:"can_edit"`
+	CanDelete bool `json:"can_delete"`
+}
+
+// CommentView is a comment as returned to one caller; Viewer is left out for anonymous requests
+type CommentView struct {
+	models.Comment
+	User   Author         `json:"user"`
+	Viewer *CommentViewer `json:"viewer,omitempty"`
+}
+
+// postViews adds the caller's reactions and permissions to posts, with one query for the reactions
+func postViews(c *gin.Context, posts []models.Post) ([]PostView, error) {
+	authorIDs := make([]uint, len(posts))
+	for i, post := range posts {
+		authorIDs[i] = post.UserID
+	}
+	profiles := authorProfiles(authorIDs)
+
+	views := make([]PostView, len(posts))
+	for i := range posts {
+		views[i].Post = posts[i]
+		views[i].User = authorOf(&posts[i].User, profiles)
+	}
+
+	actor := policy.ActorFromContext(c)
+	if actor.UserID == 0 || len(posts) == 0 {
+		return views, nil
+	}
+
+	postIDs := make([]uint, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.ID
+	}
+	var reactions []models.PostReaction
+	if err := config.DB.Where("user_id = ? AND post_id IN ?", actor.UserID, postIDs).Find(&reactions).Error; err != nil {
+		return nil, err
+	}
+	reactionByPost := make(map[uint]string, len(reactions))
+	for _, reaction := range reactions {
+		reactionByPost[reaction.PostID] = reaction.Kind
+	}
+
+	for i := range views {
+		post := &views[i].Post
+		viewer := &PostViewer{
+			IsAuthor:  post.UserID == actor.UserID,
+			CanEdit:   policy.Can(actor, policy.ActionUpdate, post),
+			CanDelete: policy.Can(actor, policy.ActionDelete, post),
+		}
+		if kind, ok := reactionByPost[post.ID]; ok {
+			viewer.Reaction = &kind
+		}
+		views[i].Viewer = viewer
+	}
+	return views, nil
+}
+
+// commentViews adds the caller's permissions to comments
+func commentViews(c *gin.Context, comments []models.Comment) []CommentView {
+	authorIDs := make([]uint, len(comments))
+	for i, comment := range comments {
+		authorIDs[i] = comment.UserID
+	}
+	profiles := authorProfiles(authorIDs)
+
+	actor := policy.ActorFromContext(c)
+	views := make([]CommentView, len(comments))
+	for i := range comments {
+		views[i].Comment = comments[i]
+		views[i].User = authorOf(&comments[i].User, profiles)
+		if actor.UserID == 0 {
+			continue
+		}
+		comment := &views[i].Comment
+		views[i].Viewer = &CommentViewer{
+			IsAuthor:  comment.UserID == actor.UserID,
+			CanEdit:   policy.Can(actor, policy.ActionUpdate, comment),
+			CanDelete: policy.Can(actor, policy.ActionDelete, comment),
+		}
+	}
+	return views
+}
@@ -1,56 +1,98 @@
 package controllers
 
 import (
-	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"gitconnect-backend/config"
 	"gitconnect-backend/models"
+	"gitconnect-backend/policy"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// CreateProfileRequest creates a profile; only admins may name another user
+type CreateProfileRequest struct {
+	UserID   uint   `json:"user_id"`
+	FullName string `json:"full_name" binding:"required,max=100"`
+	Bio      string `json:"bio" binding:"max=1000"`
+	Github   string `json:"github" binding:"omitempty,max=39,github_login"`
+}
+
+// UpdateProfileRequest edits a profile; fields left out keep their current value, full_name
+// cannot be blanked and an empty string clears bio or github
+type UpdateProfileRequest struct {
+	FullName *string `json:"full_name" binding:"omitempty,min=1,max=100"`
+	Bio      *string `json:"bio" binding:"omitempty,max=1000"`
+	Github   *string `json:"github" binding:"omitempty,max=39,github_login|len=0"`
+}
+
 // @Summary Create a new profile
-// @Description Allows an authenticated user to create a new profile
+// @Description Allows an authenticated user to create their profile (admins may create one for another user)
 // @Tags Profiles
 // @Accept json
 // @Produce json
-// @Param profile body models.Profile true "Profile Data"
+// @Param profile body CreateProfileRequest true "Profile Data"
 // @Security BearerAuth
 // @Success 201 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/profiles [post]
 func CreateProfile(c *gin.Context) {
-	var profile models.Profile
-	fmt.Println("📥 Received request to create profile") // Debug log
-
-	// Bind request JSON to profile struct
-	if err := c.ShouldBindJSON(&profile); err != nil {
-		fmt.Println("❌ Error binding JSON:", err) // Debug log
+	var input CreateProfileRequest
+	// Bind request JSON to the request struct
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	profile := models.Profile{
+		UserID:   input.UserID,
+		FullName: input.FullName,
+		Bio:      input.Bio,
+		Github:   input.Github,
+	}
+
+	// The profile belongs to the caller unless an admin creates one on someone's behalf
+	if profile.UserID == 0 {
+		profile.UserID = policy.ActorFromContext(c).UserID
+	}
+	if !policy.Authorize(c, policy.ActionCreate, &profile) {
+		return
+	}
 
 	// Check if the UserID exists in the Users table
 	var user models.User
 	if err := config.DB.First(&user, profile.UserID).Error; err != nil {
-		fmt.Println("❌ Invalid UserID, user does not exist") // Debug log
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UserID: user does not exist"})
 		return
 	}
 
+	// Every user gets a profile on registration, so usually there is one already
+	var existing int64
+	config.DB.Model(&models.Profile{}).Where("user_id = ?", profile.UserID).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Profile already exists for this user"})
+		return
+	}
+
 	// Save to database
 	if err := config.DB.Create(&profile).Error; err != nil {
-		fmt.Println("❌ Failed to create profile:", err) // Debug log
+		log.Println("❌ Failed to create profile:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create profile"})
 		return
 	}
 
-	fmt.Println("✅ Profile created successfully") // Debug log
 	c.JSON(http.StatusCreated, gin.H{"message": "Profile created successfully", "profile": profile})
 }
 
+// Repositories shown on a profile; the rest stay cached for stars and languages
+const maxProfileRepositories = 30
+
 // @Summary Get all profiles
 // @Description Fetch all profiles
 // @Tags Profiles
@@ -65,19 +107,55 @@ func GetProfiles(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"profiles": profiles})
 }
 
+// profileIDParam parses the :id path parameter; it writes the error response itself
+func profileIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid profile ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// findProfile loads the profile named by the :id path parameter; it writes the error response itself
+func findProfile(c *gin.Context) (*models.Profile, bool) {
+	id, ok := profileIDParam(c)
+	if !ok {
+		return nil, false
+	}
+	var profile models.Profile
+	if err := config.DB.First(&profile, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+		return nil, false
+	}
+	return &profile, true
+}
+
 // @Summary Get a specific profile
-// @Description Fetch a profile by ID
+// @Description Fetch a profile by ID, with its skills strongest first. When it names a GitHub account, github_profile holds the data last synced from GitHub: followers, stars, primary languages, pinned repositories and the 30 most starred repositories.
 // @Tags Profiles
 // @Accept json
 // @Produce json
 // @Param id path int true "Profile ID"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/profiles/{id} [get]
 func GetProfile(c *gin.Context) {
+	id, ok := profileIDParam(c)
+	if !ok {
+		return
+	}
 	var profile models.Profile
-	if err := config.DB.First(&profile, c.Param("id")).Error; err != nil {
+	if err := preloadProfileSkills(config.DB).
+		Preload("GithubProfile").
+		Preload("GithubProfile.Languages", func(db *gorm.DB) *gorm.DB { return db.Order("repositories DESC, name") }).
+		Preload("GithubProfile.Pinned", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("GithubProfile.Repositories", func(db *gorm.DB) *gorm.DB {
+			return db.Order("stars DESC, pushed_at DESC NULLS LAST").Limit(maxProfileRepositories)
+		}).
+		First(&profile, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
 		return
 	}
@@ -85,178 +163,99 @@ func GetProfile(c *gin.Context) {
 }
 
 // @Summary Update a profile
-// @Description Update a profile by ID
+// @Description Update a profile by ID (only its owner, or a moderator)
 // @Tags Profiles
 // @Accept json
 // @Produce json
 // @Param id path int true "Profile ID"
-// @Param profile body models.Profile true "Updated Profile Data"
+// @Param profile body UpdateProfileRequest true "Updated Profile Data"
 // @Security BearerAuth
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/profiles/{id} [put]
 func UpdateProfile(c *gin.Context) {
-	var profile models.Profile
-	if err := config.DB.First(&profile, c.Param("id")).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+	profile, ok := findProfile(c)
+	if !ok {
+		return
+	}
+	if !policy.Authorize(c, policy.ActionUpdate, profile) {
 		return
 	}
-	if err := c.ShouldBindJSON(&profile); err != nil {
+
+	// Identity and ownership are not part of the request, so the body cannot change them
+	var input UpdateProfileRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	config.DB.Save(&profile)
+	if input.FullName != nil {
+		profile.FullName = *input.FullName
+	}
+	if input.Bio != nil {
+		profile.Bio = *input.Bio
+	}
+	githubChanged := false
+	if input.Github != nil {
+		githubChanged = !strings.EqualFold(profile.Github, *input.Github)
+		profile.Github = *input.Github
+	}
+	if githubChanged {
+		profile.ClearGithubVerification()
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(profile).Error; err != nil {
+			return err
+		}
+		if !githubChanged {
+			return nil
+		}
+		// Data synced from the previous account must not show; the new one is picked up by the next
+		// sync run. A pending verification was for the old login too.
+		if err := tx.Where("profile_id = ?", profile.ID).Delete(&models.GithubProfile{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("profile_id = ?", profile.ID).Delete(&models.GithubVerificationChallenge{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Profile updated", "profile": profile})
 }
 
 // @Summary Delete a profile
-// @Description Delete a profile by ID
+// @Description Delete a profile by ID (only its owner, or an admin)
 // @Tags Profiles
 // @Accept json
 // @Produce json
 // @Param id path int true "Profile ID"
 // @Security BearerAuth
 // @Success 200 {object} map[string]interface{} 
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/profiles/{id} [delete]
 func DeleteProfile(c *gin.Context) {
-	var profile models.Profile
 	// Find profile by ID
-	if err := config.DB.First(&profile, c.Param("id")).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+	profile, ok := findProfile(c)
+	if !ok {
+		return
+	}
+	if !policy.Authorize(c, policy.ActionDelete, profile) {
 		return
 	}
 
 	// Delete the profile
-	if err := config.DB.Delete(&profile).Error; err != nil {
+	if err := config.DB.Delete(profile).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete profile"})
 		return
 	}
+	deleteProfilePicture(c.Request.Context(), profile.ProfilePicture)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Profile deleted successfully"})
 }
-
-/*
-// UploadProfileImage handles uploading a profile image for a given user.
-// @Summary Upload Profile Image
-// @Description Upload a profile image for the given user. Expects a multipart form with the field "image".
-// @Tags Profile
-// @Accept multipart/form-data
-// @Produce json
-// @Param userId path string true "User ID"
-// @Param image formData file true "Profile Image"
-// @Success 200 {object} map[string]interface{} "Profile image uploaded successfully"
-// @Failure 400 {object} map[string]interface{} "Image file is required"
-// @Failure 500 {object} map[string]interface{} "Failed to save image or update profile picture"
-// @Router /api/profiles/{userId}/image [post]
-func UploadProfileImage(c *gin.Context) {
-	userId := c.Param("userId")
-
-	file, err := c.FormFile("image")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Image file is required"})
-		return
-	}
-
-	filename := fmt.Sprintf("%d_%s", time.Now().Unix(), file.Filename)
-	uploadDir := "uploads"
-
-	if _, err := os.Stat(uploadDir); os.IsNotExist(err) {
-		os.Mkdir(uploadDir, os.ModePerm)
-	}
-
-	fullPath := filepath.Join(uploadDir, filename)
-
-	if err := c.SaveUploadedFile(file, fullPath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
-		return
-	}
-
-	if err := models.UpdateProfilePicture(userId, filename); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile picture"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"message":  "Profile image uploaded successfully",
-		"filename": filename,
-	})
-}
-
-// UpdateProfilePicture handles profile picture uploads.
-// @Summary Update Profile Picture
-// @Description Upload a new profile picture for the user.
-// @Tags Profile
-// @Accept multipart/form-data
-// @Produce json
-// @Param userId path string true "User ID"
-// @Param file formData file true "Profile Picture"
-// @Success 200 {object} map[string]interface{} "Successfully uploaded"
-// @Failure 400 {object} map[string]interface{} "File upload failed"
-// @Failure 500 {object} map[string]interface{} "Failed to save image or update profile picture"
-// @Router /api/profiles/{userId}/upload [post]
-func UpdateProfilePicture(c *gin.Context) {
-	userId := c.Param("userId")
-
-	file, err := c.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File upload failed"})
-		return
-	}
-
-	filename := fmt.Sprintf("%d_%s", time.Now().Unix(), file.Filename)
-	uploadDir := "uploads"
-
-	if _, err := os.Stat(uploadDir); os.IsNotExist(err) {
-		os.Mkdir(uploadDir, os.ModePerm)
-	}
-
-	fullPath := filepath.Join(uploadDir, filename)
-
-	if err := c.SaveUploadedFile(file, fullPath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
-		return
-	}
-
-	if err := models.UpdateProfilePicture(userId, filename); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile picture"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"message":  "Profile picture updated successfully",
-		"filename": filename,
-	})
-}
-
-// GetProfileImage serves the profile image for a given user.
-// @Summary Get Profile Image
-// @Description Serve the profile image for the given user.
-// @Tags Profile
-// @Produce image/*
-// @Param userId path string true "User ID"
-// @Success 200 {file} file "Returns the profile image"
-// @Failure 404 {object} map[string]interface{} "Profile or image not found"
-// @Router /api/profiles/{userId}/image [get]
-func GetProfileImage(c *gin.Context) {
-	userId := c.Param("userId")
-
-	profile, err := models.GetProfileByID(userId)
-	if err != nil || profile == nil || profile.ProfilePicture == "" {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Profile or image not found"})
-		return
-	}
-
-	imagePath := filepath.Join("uploads", profile.ProfilePicture)
-
-	if _, err := os.Stat(imagePath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
-		return
-	}
-
-	c.File(imagePath)
-}
-*/
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestUpdateProfileKeepsOmittedFields(t *testing.T) {
+	setupTestDB(t)
+	user := createTestUser(t, "alice")
+	var profile models.Profile
+	config.DB.Where("user_id = ?", user.ID).First(&profile)
+	config.DB.Model(&profile).Updates(map[string]interface{}{"full_name": "Alice Liddell", "bio": "Curious"})
+
+	router := gin.New()
+	router.PUT("/api/profiles/:id", authenticateAs(user), UpdateProfile)
+	path := fmt.Sprintf("/api/profiles/%d", profile.ID)
+
+	if status, body := doJSON(t, router, http.MethodPut, path, gin.H{"bio": ""}); status != http.StatusOK {
+		t.Fatalf("update bio: status %d, %v", status, body)
+	}
+	config.DB.First(&profile, profile.ID)
+	if profile.FullName != "Alice Liddell" || profile.Bio != "" {
+		t.Fatalf("after clearing bio: full name %q, bio %q", profile.FullName, profile.Bio)
+	}
+
+	if status, _ := doJSON(t, router, http.MethodPut, path, gin.H{"full_name": ""}); status != http.StatusBadRequest {
+		t.Fatalf("blank full name: status %d, want 400", status)
+	}
+	if status, body := doJSON(t, router, http.MethodPut, path, gin.H{"full_name": "Alice"}); status != http.StatusOK {
+		t.Fatalf("rename: status %d, %v", status, body)
+	}
+	config.DB.First(&profile, profile.ID)
+	if profile.FullName != "Alice" {
+		t.Fatalf("full name %q after rename", profile.FullName)
+	}
+}
@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/imaging"
+	"gitconnect-backend/models"
+	"gitconnect-backend/policy"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Largest accepted profile picture, overridable with PROFILE_PICTURE_MAX_BYTES
+const defaultProfilePictureMaxBytes = 5 << 20
+
+func profilePictureMaxBytes() int64 {
+	if value := os.Getenv("PROFILE_PICTURE_MAX_BYTES"); value != "" {
+		if parsed, err := strconv.ParseInt(value, 10, 64); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return defaultProfilePictureMaxBytes
+}
+
+// deleteProfilePicture removes a picture and its thumbnails once they are no longer referenced
+func deleteProfilePicture(ctx context.Context, key string) {
+	deleteImage(ctx, key, models.ProfilePictureSizes)
+}
+
+// profilePictureOf returns the picture key of a user's profile, so it can be deleted with the account
+func profilePictureOf(db *gorm.DB, userID uint) string {
+	var keys []string
+	db.Model(&models.Profile{}).Where("user_id = ?", userID).Pluck("profile_picture", &keys)
+	if len(keys) == 0 {
+		return ""
+	}
+	return keys[0]
+}
+
+// @Summary Upload a profile picture
+// @Description Replaces the profile picture (only its owner, or a moderator). Expects a multipart form with the field "image": a JPEG, PNG, GIF or WebP image of at most 5 MB by default. The image is decoded and re-encoded, which strips EXIF and GPS metadata, and square 64, 256 and 512 px thumbnails are generated; profile_picture_urls lists where each is served.
+// @Tags Profiles
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param image formData file true "Profile picture"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/picture [post]
+func UploadProfilePicture(c *gin.Context) {
+	profile, ok := findProfile(c)
+	if !ok {
+		return
+	}
+	if !policy.Authorize(c, policy.ActionUpdate, profile) {
+		return
+	}
+
+	data, ok := readUploadedImage(c, profilePictureMaxBytes())
+	if !ok {
+		return
+	}
+	processed, ok := processUploadedImage(c, data, models.ProfilePictureSizes, imaging.Process)
+	if !ok {
+		return
+	}
+
+	key, err := storeImage(c.Request.Context(), fmt.Sprintf("profile-pictures/%d", profile.ID), processed, models.ProfilePictureSizes)
+	if err != nil {
+		log.Println("❌ Failed to store profile picture:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
+		return
+	}
+
+	previous := profile.ProfilePicture
+	if err := config.DB.Model(profile).Update("profile_picture", key).Error; err != nil {
+		deleteProfilePicture(c.Request.Context(), key)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile picture"})
+		return
+	}
+	deleteProfilePicture(c.Request.Context(), previous)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Profile picture updated", "profile": profile})
+}
+
+// @Summary Get a profile picture
+// @Description Serves the profile picture, or one of its square thumbnails when size is given
+// @Tags Profiles
+// @Produce image/jpeg,image/png
+// @Param id path int true "Profile ID"
+// @Param size query int false "Thumbnail size in pixels: 64, 256 or 512"
+// @Success 200 {file} file "The profile picture"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/picture [get]
+func GetProfilePicture(c *gin.Context) {
+	profile, ok := findProfile(c)
+	if !ok {
+		return
+	}
+	if profile.ProfilePicture == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile has no picture"})
+		return
+	}
+
+	serveImage(c, profile.ProfilePicture, models.ProfilePictureSizes, "Profile has no picture")
+}
+
+// @Summary Remove a profile picture
+// @Description Deletes the profile picture (only its owner, or a moderator)
+// @Tags Profiles
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/picture [delete]
+func DeleteProfilePicture(c *gin.Context) {
+	profile, ok := findProfile(c)
+	if !ok {
+		return
+	}
+	if !policy.Authorize(c, policy.ActionUpdate, profile) {
+		return
+	}
+	if profile.ProfilePicture == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile has no picture"})
+		return
+	}
+
+	previous := profile.ProfilePicture
+	if err := config.DB.Model(profile).Update("profile_picture", "").Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove profile picture"})
+		return
+	}
+	deleteProfilePicture(c.Request.Context(), previous)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Profile picture removed", "profile": profile})
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"gitconnect-backend/audit"
+	"gitconnect-backend/config"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Long user agents are truncated rather than rejected
+const maxUserAgentLength = 512
+
+// createSession records a login from the device making this request
+func createSession(db *gorm.DB, c *gin.Context, userID uint) (*models.Session, error) {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	session := models.Session{
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IPAddress:  c.ClientIP(),
+		LastSeenAt: time.Now(),
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// revokeSession signs a session out: its refresh tokens are revoked and AuthMiddleware
+// rejects its access tokens from the next request on
+func revokeSession(db *gorm.DB, sessionID uint) error {
+	now := time.Now()
+	if err := db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return db.Model(&models.RefreshToken{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", now).Error
+}
+
+// @Summary List sessions
+// @Description Lists the devices the current user is logged in on; the one making this request is marked current
+// @Tags Sessions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/sessions [get]
+func ListSessions(c *gin.Context) {
+	userID, exists := middlewares.CurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var sessions []models.Session
+	if err := config.DB.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("last_seen_at DESC").Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	current := currentSessionID(c)
+	list := make([]gin.H, len(sessions))
+	for i, session := range sessions {
+		list[i] = gin.H{
+			"id":           session.ID,
+			"user_agent":   session.UserAgent,
+			"ip_address":   session.IPAddress,
+			"created_at":   session.CreatedAt,
+			"last_seen_at": session.LastSeenAt,
+			"current":      session.ID == current,
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": list})
+}
+
+// @Summary Revoke a session
+// @Description Signs out one device; its access and refresh tokens stop working immediately
+// @Tags Sessions
+// @Produce json
+// @Param id path int true "Session ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/sessions/{id} [delete]
+func RevokeSession(c *gin.Context) {
+	userID, exists := middlewares.CurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	// Scoped to the caller, so other users' sessions look like they do not exist
+	var session models.Session
+	if err := config.DB.Where("user_id = ?", userID).First(&session, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if err := revokeSession(config.DB, session.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+	audit.Log(c, models.AuditSessionRevoked, session.UserID, audit.Details{"session_id": session.ID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
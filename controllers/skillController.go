@@ -0,0 +1,540 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/models"
+	"gitconnect-backend/policy"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Skills a profile can list
+const maxProfileSkills = 30
+
+// SkillInput is one skill of a profile, under any spelling; "golang" is stored as Go
+type SkillInput struct {
+	Name  string `json:"name" binding:"required,max=50,skill_name"`
+	Level string `json:"level" binding:"omitempty,oneof=beginner intermediate advanced expert"` // Defaults to intermediate
+}
+
+// SetSkillsRequest replaces all of a profile's skills
+type SetSkillsRequest struct {
+	Skills []SkillInput `json:"skills" binding:"max=30,dive"`
+}
+
+// SkillAliasRequest adds another spelling of a skill
+type SkillAliasRequest struct {
+	Alias string `json:"alias" binding:"required,max=50,skill_name"`
+}
+
+// SkillSummary is a skill with the number of profiles listing it
+type SkillSummary struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	Profiles int64  `json:"profiles"`
+}
+
+// SkilledProfile is a profile found by skill, with its level in that skill
+type SkilledProfile struct {
+	models.Profile
+	Level string `json:"level"`
+}
+
+var (
+	errInvalidSkill  = errors.New("invalid skill name")
+	errTooManySkills = errors.New("too many skills")
+	errAliasTaken    = errors.New("alias already in use")
+)
+
+// levelRank orders a level column from expert down in SQL
+func levelRank(column string) string {
+	var b strings.Builder
+	b.WriteString("CASE " + column)
+	for rank, level := range models.SkillLevels {
+		fmt.Fprintf(&b, " WHEN '%s' THEN %d", level, rank)
+	}
+	b.WriteString(" ELSE -1 END DESC")
+	return b.String()
+}
+
+// preloadProfileSkills loads a profile's skills, strongest first
+func preloadProfileSkills(db *gorm.DB) *gorm.DB {
+	return db.Preload("Skills", func(db *gorm.DB) *gorm.DB {
+		return db.Order(levelRank("level")).Order("created_at")
+	}).Preload("Skills.Skill")
+}
+
+// lookupSkill finds the skill a slug or alias names
+func lookupSkill(db *gorm.DB, slug string) (*models.Skill, error) {
+	var alias models.SkillAlias
+	err := db.Preload("Skill").Where("alias = ?", slug).First(&alias).Error
+	if err == nil && alias.Skill != nil {
+		return alias.Skill, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var skill models.Skill
+	if err := db.Where("slug = ?", slug).First(&skill).Error; err != nil {
+		return nil, err
+	}
+	return &skill, nil
+}
+
+// resolveSkill canonicalizes a skill name, creating the skill as spelled when it is new
+func resolveSkill(tx *gorm.DB, name string) (*models.Skill, error) {
+	slug := models.SkillSlug(name)
+	if slug == "" {
+		return nil, errInvalidSkill
+	}
+	skill, err := lookupSkill(tx, slug)
+	if err == nil {
+		return skill, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Someone else may be adding the same skill; either way the row with this slug is the one
+	created := models.Skill{Name: strings.Join(strings.Fields(name), " "), Slug: slug}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&created).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("slug = ?", slug).First(&created).Error; err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// findSkill loads the skill named by the :slug path parameter, following aliases; it writes the error response itself
+func findSkill(c *gin.Context) (*models.Skill, bool) {
+	skill, err := lookupSkill(config.DB, models.SkillSlug(c.Param("slug")))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Skill not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch skill"})
+		return nil, false
+	}
+	return skill, true
+}
+
+// profileSkills returns a profile's skills after a change, strongest first
+func profileSkills(profileID uint) ([]models.ProfileSkill, error) {
+	var profile models.Profile
+	if err := preloadProfileSkills(config.DB).First(&profile, profileID).Error; err != nil {
+		return nil, err
+	}
+	return profile.Skills, nil
+}
+
+// @Summary List skills
+// @Description Lists skills with how many profiles list them, most popular first. q matches the start of a skill's name or of one of its aliases, so "golang" finds Go.
+// @Tags Skills
+// @Produce json
+// @Param q query string false "Search"
+// @Param page query int false "Page number"
+// @Param limit query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /api/skills [get]
+func ListSkills(c *gin.Context) {
+	page, limit := pagination(c)
+
+	query := config.DB.Model(&models.Skill{})
+	if q := models.SkillSlug(c.Query("q")); q != "" {
+		// Slugs are letters, digits and dashes, so q needs no LIKE escaping
+		query = query.Where("skills.slug LIKE ? OR skills.id IN (?)", q+"%",
+			config.DB.Model(&models.SkillAlias{}).Select("skill_id").Where("alias LIKE ?", q+"%"))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch skills"})
+		return
+	}
+
+	var skills []SkillSummary
+	if err := query.
+		Select("skills.id, skills.name, skills.slug, COUNT(profile_skills.profile_id) AS profiles").
+		Joins("LEFT JOIN profile_skills ON profile_skills.skill_id = skills.id").
+		Group("skills.id").
+		Order("profiles DESC, skills.name").
+		Offset((page - 1) * limit).Limit(limit).
+		Scan(&skills).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch skills"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"skills": skills, "page": page, "limit": limit, "total": total})
+}
+
+// @Summary List profiles by skill
+// @Description Lists the profiles that list a skill, strongest first. The skill can be named by its slug or an alias (e.g. /api/skills/golang/profiles).
+// @Tags Skills
+// @Produce json
+// @Param slug path string true "Skill slug or alias"
+// @Param min_level query string false "Lowest level to include: beginner, intermediate, advanced or expert"
+// @Param page query int false "Page number"
+// @Param limit query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/skills/{slug}/profiles [get]
+func GetSkillProfiles(c *gin.Context) {
+	skill, ok := findSkill(c)
+	if !ok {
+		return
+	}
+	levels := models.SkillLevels
+	if minLevel := c.Query("min_level"); minLevel != "" {
+		if levels = models.LevelsFrom(minLevel); levels == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_level must be beginner, intermediate, advanced or expert"})
+			return
+		}
+	}
+	page, limit := pagination(c)
+
+	var links []models.ProfileSkill
+	query := config.DB.Where("skill_id = ? AND level IN ?", skill.ID, levels)
+	var total int64
+	if err := query.Model(&models.ProfileSkill{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profiles"})
+		return
+	}
+	if err := query.Order(levelRank("level")).Order("profile_id").
+		Offset((page - 1) * limit).Limit(limit).Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profiles"})
+		return
+	}
+
+	profileIDs := make([]uint, len(links))
+	for i, link := range links {
+		profileIDs[i] = link.ProfileID
+	}
+	var profiles []models.Profile
+	if err := preloadProfileSkills(config.DB).Where("id IN ?", profileIDs).Find(&profiles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profiles"})
+		return
+	}
+	byID := make(map[uint]models.Profile, len(profiles))
+	for _, profile := range profiles {
+		byID[profile.ID] = profile
+	}
+
+	results := make([]SkilledProfile, 0, len(links))
+	for _, link := range links {
+		if profile, ok := byID[link.ProfileID]; ok {
+			results = append(results, SkilledProfile{Profile: profile, Level: link.Level})
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"skill": skill, "profiles": results, "page": page, "limit": limit, "total": total})
+}
+
+// @Summary Replace profile skills
+// @Description Replaces the skills of a profile (only its owner, or a moderator). Names are canonicalized, so "golang" is stored as Go; unknown skills are created as spelled. Listing the same skill twice keeps the last level.
+// @Tags Skills
+// @Accept json
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param skills body SetSkillsRequest true "Skills"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/skills [put]
+func SetProfileSkills(c *gin.Context) {
+	profile, ok := findProfile(c)
+	if !ok {
+		return
+	}
+	if !policy.Authorize(c, policy.ActionUpdate, profile) {
+		return
+	}
+	var input SetSkillsRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		links := make([]models.ProfileSkill, 0, len(input.Skills))
+		index := make(map[uint]int, len(input.Skills))
+		for _, item := range input.Skills {
+			skill, err := resolveSkill(tx, item.Name)
+			if err != nil {
+				return err
+			}
+			link := models.ProfileSkill{ProfileID: profile.ID, SkillID: skill.ID, Level: item.Level}
+			if link.Level == "" {
+				link.Level = models.LevelIntermediate
+			}
+			if i, seen := index[skill.ID]; seen {
+				links[i] = link
+				continue
+			}
+			index[skill.ID] = len(links)
+			links = append(links, link)
+		}
+
+		if err := tx.Where("profile_id = ?", profile.ID).Delete(&models.ProfileSkill{}).Error; err != nil {
+			return err
+		}
+		if len(links) == 0 {
+			return nil
+		}
+		return tx.Omit(clause.Associations).Create(&links).Error
+	})
+	if errors.Is(err, errInvalidSkill) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Skill names need at least one letter or digit"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update skills"})
+		return
+	}
+
+	skills, err := profileSkills(profile.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch skills"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Skills updated", "skills": skills})
+}
+
+// @Summary Add a profile skill
+// @Description Adds a skill to a profile, or changes its level if it is already listed (only its owner, or a moderator). The name is canonicalized as for replacing skills.
+// @Tags Skills
+// @Accept json
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param skill body SkillInput true "Skill"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/skills [post]
+func AddProfileSkill(c *gin.Context) {
+	profile, ok := findProfile(c)
+	if !ok {
+		return
+	}
+	if !policy.Authorize(c, policy.ActionUpdate, profile) {
+		return
+	}
+	var input SkillInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Level == "" {
+		input.Level = models.LevelIntermediate
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Locking the profile serializes concurrent additions, so the limit holds
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Profile{}, profile.ID).Error; err != nil {
+			return err
+		}
+		skill, err := resolveSkill(tx, input.Name)
+		if err != nil {
+			return err
+		}
+
+		var existing models.ProfileSkill
+		err = tx.Where("profile_id = ? AND skill_id = ?", profile.ID, skill.ID).First(&existing).Error
+		if err == nil {
+			return tx.Model(&existing).Update("level", input.Level).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.ProfileSkill{}).Where("profile_id = ?", profile.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= maxProfileSkills {
+			return errTooManySkills
+		}
+		link := models.ProfileSkill{ProfileID: profile.ID, SkillID: skill.ID, Level: input.Level}
+		return tx.Omit(clause.Associations).Create(&link).Error
+	})
+	if errors.Is(err, errInvalidSkill) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Skill names need at least one letter or digit"})
+		return
+	}
+	if errors.Is(err, errTooManySkills) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("A profile can list at most %d skills", maxProfileSkills)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add skill"})
+		return
+	}
+
+	skills, err := profileSkills(profile.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch skills"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Skill saved", "skills": skills})
+}
+
+// @Summary Remove a profile skill
+// @Description Removes a skill from a profile (only its owner, or a moderator). The skill can be named by its slug or an alias.
+// @Tags Skills
+// @Produce json
+// @Param id path int true "Profile ID"
+// @Param slug path string true "Skill slug or alias"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/profiles/{id}/skills/{slug} [delete]
+func RemoveProfileSkill(c *gin.Context) {
+	profile, ok := findProfile(c)
+	if !ok {
+		return
+	}
+	if !policy.Authorize(c, policy.ActionUpdate, profile) {
+		return
+	}
+	skill, ok := findSkill(c)
+	if !ok {
+		return
+	}
+
+	result := config.DB.Where("profile_id = ? AND skill_id = ?", profile.ID, skill.ID).Delete(&models.ProfileSkill{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove skill"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile does not list this skill"})
+		return
+	}
+
+	skills, err := profileSkills(profile.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch skills"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Skill removed", "skills": skills})
+}
+
+// @Summary Add a skill alias
+// @Description Makes another spelling resolve to a skill (moderators and admins). If the alias is itself an existing skill, that skill is merged in: its profiles and aliases move over and it is deleted. Profiles listing both keep their level in the target skill.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Skill ID"
+// @Param alias body SkillAliasRequest true "Alias"
+// @Security BearerAuth
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/admin/skills/{id}/aliases [post]
+func AdminAddSkillAlias(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid skill ID"})
+		return
+	}
+	var skill models.Skill
+	if err := config.DB.First(&skill, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Skill not found"})
+		return
+	}
+	var input SkillAliasRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	alias := models.SkillSlug(input.Alias)
+	if alias == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Aliases need at least one letter or digit"})
+		return
+	}
+	if alias == skill.Slug {
+		c.JSON(http.StatusConflict, gin.H{"error": "Alias is the skill's own name"})
+		return
+	}
+
+	merged := ""
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var existing models.SkillAlias
+		err := tx.Where("alias = ?", alias).First(&existing).Error
+		if err == nil {
+			return errAliasTaken
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var other models.Skill
+		err = tx.Where("slug = ?", alias).First(&other).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil {
+			if err := mergeSkill(tx, &other, &skill); err != nil {
+				return err
+			}
+			merged = other.Name
+		}
+		return tx.Create(&models.SkillAlias{SkillID: skill.ID, Alias: alias}).Error
+	})
+	if errors.Is(err, errAliasTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Alias already belongs to a skill"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add alias"})
+		return
+	}
+
+	response := gin.H{"message": "Alias added", "skill": skill, "alias": alias}
+	if merged != "" {
+		response["merged"] = merged
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// mergeSkill moves the profiles and aliases of from onto into and deletes from
+func mergeSkill(tx *gorm.DB, from, into *models.Skill) error {
+	// Profiles already listing the target keep that entry
+	if err := tx.Where("skill_id = ? AND profile_id IN (?)", from.ID,
+		tx.Model(&models.ProfileSkill{}).Select("profile_id").Where("skill_id = ?", into.ID)).
+		Delete(&models.ProfileSkill{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.ProfileSkill{}).Where("skill_id = ?", from.ID).Update("skill_id", into.ID).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.SkillAlias{}).Where("skill_id = ?", from.ID).Update("skill_id", into.ID).Error; err != nil {
+		return err
+	}
+	return tx.Delete(from).Error
+}
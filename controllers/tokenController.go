@@ -0,0 +1,365 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"gitconnect-backend/audit"
+	"gitconnect-backend/config"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errRefreshTokenInvalid = errors.New("invalid refresh token")
+	errRefreshTokenReused  = errors.New("refresh token reuse detected")
+	errAccountSuspended    = errors.New("account suspended")
+)
+
+// RefreshRequest is the body accepted by the refresh endpoint
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// tokenPair is what every successful authentication hands back to the client
+type tokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+func (p tokenPair) JSON() gin.H {
+	return gin.H{
+		"token":         p.AccessToken,
+		"refresh_token": p.RefreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(utils.AccessTokenTTL().Seconds()),
+	}
+}
+
+// issueTokenPair starts a session for the device the request came from and creates an access
+// token and a refresh token starting a new token family within it
+func issueTokenPair(db *gorm.DB, c *gin.Context, user *models.User) (tokenPair, error) {
+	session, err := createSession(db, c, user.ID)
+	if err != nil {
+		return tokenPair{}, err
+	}
+	familyID, err := utils.GenerateOpaqueToken(16)
+	if err != nil {
+		return tokenPair{}, err
+	}
+	refresh, _, err := createRefreshToken(db, user.ID, familyID, &session.ID)
+	if err != nil {
+		return tokenPair{}, err
+	}
+	access, err := utils.GenerateToken(user.ID, user.Role, session.ID)
+	if err != nil {
+		return tokenPair{}, err
+	}
+	return tokenPair{AccessToken: access, RefreshToken: refresh}, nil
+}
+
+// createRefreshToken stores the hash of a fresh refresh token and returns the raw value
+func createRefreshToken(db *gorm.DB, userID uint, familyID string, sessionID *uint) (string, *models.RefreshToken, error) {
+	raw, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	record := models.RefreshToken{
+		UserID:    userID,
+		TokenHash: utils.HashToken(raw),
+		FamilyID:  familyID,
+		SessionID: sessionID,
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL()),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return "", nil, err
+	}
+	return raw, &record, nil
+}
+
+// revokeTokenFamily revokes every still-valid refresh token descended from the same login
+func revokeTokenFamily(db *gorm.DB, familyID string) error {
+	return db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// revokeRefreshTokenFamily revokes the family a refresh token belongs to, along with its session
+func revokeRefreshTokenFamily(db *gorm.DB, token *models.RefreshToken) error {
+	if token.SessionID != nil {
+		if err := revokeSession(db, *token.SessionID); err != nil {
+			return err
+		}
+	}
+	return revokeTokenFamily(db, token.FamilyID)
+}
+
+// rotateRefreshToken consumes a refresh token and issues its successor in the same family.
+// Presenting a token that was already used revokes the whole family.
+func rotateRefreshToken(raw string) (tokenPair, error) {
+	var pair tokenPair
+	// Set when the family was revoked: that change must commit, yet the caller still gets an error
+	var rejected error
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var current models.RefreshToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", utils.HashToken(raw)).
+			First(&current).Error
+		if err != nil {
+			return errRefreshTokenInvalid
+		}
+
+		now := time.Now()
+		if current.UsedAt != nil || current.RevokedAt != nil {
+			// Someone is replaying a consumed token: kill the family but commit that change
+			rejected = errRefreshTokenReused
+			return revokeRefreshTokenFamily(tx, &current)
+		}
+		if !current.IsActive(now) {
+			return errRefreshTokenInvalid
+		}
+
+		// Re-read the user so a role change or suspension is reflected in the new access token
+		var user models.User
+		if err := tx.First(&user, current.UserID).Error; err != nil {
+			return errRefreshTokenInvalid
+		}
+		if user.IsSuspended() {
+			rejected = errAccountSuspended
+			return revokeRefreshTokenFamily(tx, &current)
+		}
+
+		// The session may have been signed out from another device
+		var sessionID uint
+		if current.SessionID != nil {
+			var session models.Session
+			if err := tx.First(&session, *current.SessionID).Error; err != nil || !session.IsActive() {
+				return errRefreshTokenInvalid
+			}
+			if err := tx.Model(&session).Update("last_seen_at", now).Error; err != nil {
+				return err
+			}
+			sessionID = session.ID
+		}
+
+		next, record, err := createRefreshToken(tx, current.UserID, current.FamilyID, current.SessionID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&current).Updates(map[string]interface{}{
+			"used_at":        now,
+			"replaced_by_id": record.ID,
+		}).Error; err != nil {
+			return err
+		}
+
+		access, err := utils.GenerateToken(user.ID, user.Role, sessionID)
+		if err != nil {
+			return err
+		}
+		pair = tokenPair{AccessToken: access, RefreshToken: next}
+		return nil
+	})
+	if err != nil {
+		return tokenPair{}, err
+	}
+	if rejected != nil {
+		return tokenPair{}, rejected
+	}
+	return pair, nil
+}
+
+// @Summary Refresh access token
+// @Description Exchanges a refresh token for a new access token and a rotated refresh token. Replaying a used refresh token revokes every token from that login.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body RefreshRequest true "Refresh token"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/refresh [post]
+func RefreshToken(c *gin.Context) {
+	var input RefreshRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pair, err := rotateRefreshToken(input.RefreshToken)
+	switch {
+	case errors.Is(err, errRefreshTokenReused):
+		audit.Log(c, models.AuditRefreshReuse, refreshTokenOwner(input.RefreshToken), nil)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has already been used; please log in again"})
+		return
+	case errors.Is(err, errAccountSuspended):
+		c.JSON(http.StatusForbidden, gin.H{"error": "This account has been suspended"})
+		return
+	case errors.Is(err, errRefreshTokenInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	response := pair.JSON()
+	response["message"] = "Token refreshed"
+	c.JSON(http.StatusOK, response)
+}
+
+// refreshTokenOwner returns the user a refresh token was issued to, or 0 if it is unknown
+func refreshTokenOwner(raw string) uint {
+	var token models.RefreshToken
+	if err := config.DB.Select("user_id").Where("token_hash = ?", utils.HashToken(raw)).First(&token).Error; err != nil {
+		return 0
+	}
+	return token.UserID
+}
+
+// revokeAllUserTokens logs a user out everywhere: every access token issued so far
+// stops working and every session and refresh token is revoked. It runs after every role change,
+// suspension and deletion, so the cached principal is dropped here too.
+func revokeAllUserTokens(userID uint) error {
+	middlewares.ForgetUser(userID)
+	if err := utils.Revocations.RevokeAllForUser(userID); err != nil {
+		return err
+	}
+	if err := config.DB.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return config.DB.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// revokeCurrentToken blacklists the access token that authenticated this request
+func revokeCurrentToken(c *gin.Context) error {
+	value, exists := c.Get("claims")
+	if !exists {
+		return nil
+	}
+	claims := value.(*utils.Claims)
+	return utils.Revocations.RevokeToken(claims.Id, claims.UserID, time.Unix(claims.ExpiresAt, 0))
+}
+
+// currentSessionID returns the session behind this request's access token, or 0 if there is none
+func currentSessionID(c *gin.Context) uint {
+	value, exists := c.Get("claims")
+	if !exists {
+		return 0
+	}
+	return value.(*utils.Claims).SessionID
+}
+
+// LogoutRequest optionally carries the refresh token to revoke alongside the access token
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// @Summary Logout
+// @Description Ends the session behind the access token used for this request and, if supplied, the session of the given refresh token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body LogoutRequest false "Refresh token to revoke"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/logout [post]
+func Logout(c *gin.Context) {
+	userID, exists := middlewares.CurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// The body is optional; ignore bind errors from an empty request
+	var input LogoutRequest
+	_ = c.ShouldBindJSON(&input)
+
+	if err := revokeCurrentToken(c); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	if sessionID := currentSessionID(c); sessionID != 0 {
+		if err := revokeSession(config.DB, sessionID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+	}
+
+	if input.RefreshToken != "" {
+		var token models.RefreshToken
+		err := config.DB.Where("token_hash = ? AND user_id = ?", utils.HashToken(input.RefreshToken), userID).
+			First(&token).Error
+		if err == nil {
+			if err := revokeRefreshTokenFamily(config.DB, &token); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+				return
+			}
+		}
+	}
+
+	audit.Log(c, models.AuditLogout, userID, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// @Summary Logout everywhere
+// @Description Ends every session and revokes every access and refresh token issued to the current user
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/logout-all [post]
+func LogoutAll(c *gin.Context) {
+	userID, exists := middlewares.CurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := revokeCurrentToken(c); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	if err := revokeAllUserTokens(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	audit.Log(c, models.AuditLogoutAll, userID, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out from all devices"})
+}
+
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying GitConnect access tokens; select the key by the token's kid header. HMAC keys are never published.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /.well-known/jwks.json [get]
+func JWKS(c *gin.Context) {
+	keys, err := utils.JWKS()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load keys"})
+		return
+	}
+
+	// Verifiers poll this; let them cache it briefly so rotations still propagate quickly
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/models"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func setupRefreshTest(t *testing.T) (*gin.Engine, *models.User) {
+	t.Helper()
+	setupTestDB(t)
+	router := gin.New()
+	router.POST("/api/auth/refresh", RefreshToken)
+	return router, createTestUser(t, "alice")
+}
+
+// refresh exchanges a refresh token, returning the new pair's refresh token
+func refresh(t *testing.T, router *gin.Engine, raw string) (int, string) {
+	t.Helper()
+	status, body := doJSON(t, router, http.MethodPost, "/api/auth/refresh", RefreshRequest{RefreshToken: raw})
+	next, _ := body["refresh_token"].(string)
+	return status, next
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	router, user := setupRefreshTest(t)
+	pair, err := issueTokenPair(config.DB, newLoginContext(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, body := doJSON(t, router, http.MethodPost, "/api/auth/refresh", RefreshRequest{RefreshToken: pair.RefreshToken})
+	if status != http.StatusOK {
+		t.Fatalf("refresh: status %d, %v", status, body)
+	}
+	next, _ := body["refresh_token"].(string)
+	if next == "" || next == pair.RefreshToken {
+		t.Fatalf("refresh token was not rotated: %v", body)
+	}
+	access, _ := body["token"].(string)
+	claims, err := utils.ValidateToken(access)
+	if err != nil || claims.UserID != user.ID {
+		t.Fatalf("access token for user %d: %+v, %v", user.ID, claims, err)
+	}
+
+	// The old token is spent and points at its successor, which shares its family
+	var old, successor models.RefreshToken
+	config.DB.Where("token_hash = ?", utils.HashToken(pair.RefreshToken)).First(&old)
+	config.DB.Where("token_hash = ?", utils.HashToken(next)).First(&successor)
+	if old.UsedAt == nil || old.ReplacedByID == nil || *old.ReplacedByID != successor.ID {
+		t.Fatalf("old token not marked used and replaced: %+v", old)
+	}
+	if successor.FamilyID != old.FamilyID || !successor.IsActive(time.Now()) {
+		t.Fatalf("successor = %+v, want an active token in family %s", successor, old.FamilyID)
+	}
+
+	// The successor rotates in turn
+	if status, third := refresh(t, router, next); status != http.StatusOK || third == "" {
+		t.Fatalf("second refresh: status %d", status)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	router, user := setupRefreshTest(t)
+	stolen, err := issueTokenPair(config.DB, newLoginContext(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherLogin, err := issueTokenPair(config.DB, newLoginContext(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, current := refresh(t, router, stolen.RefreshToken)
+	if status != http.StatusOK {
+		t.Fatalf("first refresh: status %d", status)
+	}
+
+	// Replaying the consumed token is reuse, and takes the legitimate successor down with it
+	if status, _ := refresh(t, router, stolen.RefreshToken); status != http.StatusUnauthorized {
+		t.Fatalf("replay: status %d, want 401", status)
+	}
+	if status, _ := refresh(t, router, current); status != http.StatusUnauthorized {
+		t.Fatalf("successor after reuse: status %d, want 401", status)
+	}
+	var active int64
+	config.DB.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND used_at IS NULL AND revoked_at IS NULL", user.ID).Count(&active)
+	if active != 1 {
+		t.Fatalf("%d active refresh tokens, want only the other login's", active)
+	}
+
+	// A separate login is a separate family and keeps working
+	if status, _ := refresh(t, router, otherLogin.RefreshToken); status != http.StatusOK {
+		t.Fatalf("other login: status %d", status)
+	}
+}
+
+func TestRefreshRejectsInvalidTokens(t *testing.T) {
+	router, user := setupRefreshTest(t)
+
+	if status, _ := refresh(t, router, "not-a-token"); status != http.StatusUnauthorized {
+		t.Fatalf("unknown token: status %d, want 401", status)
+	}
+	if status, body := doJSON(t, router, http.MethodPost, "/api/auth/refresh", gin.H{}); status != http.StatusBadRequest {
+		t.Fatalf("missing token: status %d, %v", status, body)
+	}
+
+	pair, err := issueTokenPair(config.DB, newLoginContext(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.DB.Model(&models.RefreshToken{}).Where("token_hash = ?", utils.HashToken(pair.RefreshToken)).
+		Update("expires_at", time.Now().Add(-time.Minute))
+	if status, _ := refresh(t, router, pair.RefreshToken); status != http.StatusUnauthorized {
+		t.Fatalf("expired token: status %d, want 401", status)
+	}
+}
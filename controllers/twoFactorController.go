@@ -0,0 +1,439 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"gitconnect-backend/audit"
+	"gitconnect-backend/config"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	purposeTwoFactorChallenge = "2fa_challenge"
+	twoFactorChallengeTTL     = 5 * time.Minute
+	twoFactorIssuer           = "GitConnect"
+	recoveryCodeCount         = 10
+)
+
+var errSecondFactorInvalid = errors.New("invalid two-factor code")
+
+// TwoFactorCodeRequest confirms enrollment with a code from the authenticator app
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorVerifyRequest completes a login challenge with either a TOTP code or a recovery code
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// TwoFactorReauthRequest re-authenticates the user before a sensitive 2FA change
+type TwoFactorReauthRequest struct {
+	Password     string `json:"password" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// startTwoFactorChallenge answers a correct password with a short-lived challenge token
+func startTwoFactorChallenge(c *gin.Context, user *models.User) {
+	challenge, err := utils.GeneratePurposeToken(user.ID, "", purposeTwoFactorChallenge, twoFactorChallengeTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":             "Two-factor authentication required",
+		"two_factor_required": true,
+		"challenge_token":     challenge,
+		"expires_in":          int(twoFactorChallengeTTL.Seconds()),
+	})
+}
+
+// checkSecondFactor validates a TOTP or recovery code for a user with 2FA enabled and
+// consumes it, so the same code cannot be used twice
+func checkSecondFactor(user *models.User, code, recoveryCode string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the user row so concurrent attempts cannot both accept the same code
+		var locked models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, user.ID).Error; err != nil {
+			return err
+		}
+		if !locked.TOTPEnabled {
+			return errSecondFactorInvalid
+		}
+
+		if code != "" {
+			step, ok := utils.ValidateTOTP(locked.TOTPSecret, code, time.Now(), locked.TOTPLastStep)
+			if !ok {
+				return errSecondFactorInvalid
+			}
+			return tx.Model(&locked).Update("totp_last_step", step).Error
+		}
+
+		if recoveryCode != "" {
+			hash := utils.HashToken(utils.NormalizeRecoveryCode(recoveryCode))
+			result := tx.Model(&models.RecoveryCode{}).
+				Where("user_id = ? AND code_hash = ? AND used_at IS NULL", locked.ID, hash).
+				Update("used_at", time.Now())
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errSecondFactorInvalid
+			}
+			return nil
+		}
+
+		return errSecondFactorInvalid
+	})
+}
+
+// replaceRecoveryCodes invalidates existing recovery codes and stores hashes of fresh ones
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	records := make([]models.RecoveryCode, len(codes))
+	for i, code := range codes {
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: utils.HashToken(utils.NormalizeRecoveryCode(code))}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// reauthenticate checks the password plus a second factor; it writes the error response itself
+func reauthenticate(c *gin.Context, user *models.User, input TwoFactorReauthRequest) bool {
+	if !allowAttempt(c, twoFactorKey(user.ID), "") {
+		return false
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+		_, _ = AccountThrottle.Fail(twoFactorKey(user.ID))
+		audit.Log(c, models.AuditLoginFailed, user.ID, audit.Details{"reason": "reauthentication"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		return false
+	}
+	if err := checkSecondFactor(user, input.Code, input.RecoveryCode); err != nil {
+		if errors.Is(err, errSecondFactorInvalid) {
+			_, _ = AccountThrottle.Fail(twoFactorKey(user.ID))
+			audit.Log(c, models.AuditTwoFactorFailed, user.ID, nil)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify two-factor code"})
+		}
+		return false
+	}
+	return true
+}
+
+// loadCurrentUser re-reads the authenticated user from the database, for handlers that check or
+// change credentials and cannot work from the cached principal; it writes the error response itself
+func loadCurrentUser(c *gin.Context) (*models.User, bool) {
+	userID, exists := middlewares.CurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// @Summary Two-factor status
+// @Description Reports whether 2FA is enabled and how many unused recovery codes remain
+// @Tags Two-Factor
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Router /api/auth/2fa [get]
+func TwoFactorStatus(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	var remaining int64
+	config.DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).Count(&remaining)
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  user.TOTPEnabled,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// @Summary Start 2FA enrollment
+// @Description Generates a TOTP secret and otpauth URI. 2FA is not active until confirmed with /api/auth/2fa/enable.
+// @Tags Two-Factor
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/2fa/setup [post]
+func SetupTwoFactor(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+	if err := config.DB.Model(user).Updates(map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor setup"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": utils.TOTPURI(secret, user.Email, twoFactorIssuer),
+	})
+}
+
+// @Summary Enable 2FA
+// @Description Confirms enrollment with a code from the authenticator app and returns one-time recovery codes. The codes are shown only once.
+// @Tags Two-Factor
+// @Accept json
+// @Produce json
+// @Param body body TwoFactorCodeRequest true "Current TOTP code"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/2fa/enable [post]
+func EnableTwoFactor(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	var input TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start two-factor setup first"})
+		return
+	}
+
+	step, valid := utils.ValidateTOTP(user.TOTPSecret, input.Code, time.Now(), user.TOTPLastStep)
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+		}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+	audit.Log(c, models.AuditTwoFactorEnabled, user.ID, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// @Summary Verify 2FA login challenge
+// @Description Completes a login that returned two_factor_required, using a TOTP code or a one-time recovery code
+// @Tags Two-Factor
+// @Accept json
+// @Produce json
+// @Param body body TwoFactorVerifyRequest true "Challenge token and code"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 423 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/2fa/verify [post]
+func VerifyTwoFactor(c *gin.Context) {
+	var input TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Code == "" && input.RecoveryCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A code or recovery code is required"})
+		return
+	}
+
+	claims, err := utils.ValidatePurposeToken(input.ChallengeToken, purposeTwoFactorChallenge)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, claims.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+
+	// A 6-digit code is easy to brute-force without a limit on guesses
+	if !allowAttempt(c, twoFactorKey(user.ID), loginIPKey(c)) {
+		return
+	}
+
+	if err := checkSecondFactor(&user, input.Code, input.RecoveryCode); err != nil {
+		if errors.Is(err, errSecondFactorInvalid) {
+			_, _ = AccountThrottle.Fail(twoFactorKey(user.ID))
+			_, _ = IPThrottle.Fail(loginIPKey(c))
+			audit.Log(c, models.AuditTwoFactorFailed, user.ID, nil)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify two-factor code"})
+		}
+		return
+	}
+	_ = AccountThrottle.Reset(twoFactorKey(user.ID))
+
+	completeLogin(c, &user, "2fa", "Login successful")
+}
+
+// @Summary Disable 2FA
+// @Description Turns off two-factor authentication after re-authenticating with the password and a current code or recovery code
+// @Tags Two-Factor
+// @Accept json
+// @Produce json
+// @Param body body TwoFactorReauthRequest true "Password and second factor"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/2fa/disable [post]
+func DisableTwoFactor(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	var input TwoFactorReauthRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if !reauthenticate(c, user, input) {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_enabled":   false,
+			"totp_secret":    "",
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+	audit.Log(c, models.AuditTwoFactorDisabled, user.ID, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// @Summary Regenerate recovery codes
+// @Description Replaces all recovery codes after re-authenticating. The new codes are shown only once.
+// @Tags Two-Factor
+// @Accept json
+// @Produce json
+// @Param body body TwoFactorReauthRequest true "Password and second factor"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/2fa/recovery-codes [post]
+func RegenerateRecoveryCodes(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	var input TwoFactorReauthRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if !reauthenticate(c, user, input) {
+		return
+	}
+
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+	audit.Log(c, models.AuditRecoveryCodesReset, user.ID, nil)
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
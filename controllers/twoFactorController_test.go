@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/models"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func setupTwoFactorTest(t *testing.T) (*gin.Engine, *models.User) {
+	t.Helper()
+	setupTestDB(t)
+	user := createTestUser(t, "alice")
+	setPassword(t, user, "correct-password")
+
+	router := gin.New()
+	router.POST("/api/auth/login", Login)
+	router.POST("/api/auth/2fa/verify", VerifyTwoFactor)
+	enrolled := router.Group("/api/auth/2fa", authenticateAs(user))
+	enrolled.POST("/setup", SetupTwoFactor)
+	enrolled.POST("/enable", EnableTwoFactor)
+	return router, user
+}
+
+// enrollTwoFactor turns on 2FA through the API and returns the secret and recovery codes
+func enrollTwoFactor(t *testing.T, router *gin.Engine) (string, []string) {
+	t.Helper()
+	status, body := doJSON(t, router, http.MethodPost, "/api/auth/2fa/setup", nil)
+	if status != http.StatusOK {
+		t.Fatalf("2fa setup: status %d, %v", status, body)
+	}
+	secret := body["secret"].(string)
+
+	code, _ := utils.TOTPCode(secret, time.Now())
+	status, body = doJSON(t, router, http.MethodPost, "/api/auth/2fa/enable", TwoFactorCodeRequest{Code: code})
+	if status != http.StatusOK {
+		t.Fatalf("2fa enable: status %d, %v", status, body)
+	}
+	var recovery []string
+	for _, code := range body["recovery_codes"].([]interface{}) {
+		recovery = append(recovery, code.(string))
+	}
+	return secret, recovery
+}
+
+// passwordStep logs in with the correct password, which gets an account with 2FA a challenge token
+func passwordStep(t *testing.T, router *gin.Engine, user *models.User) string {
+	t.Helper()
+	status, body := doJSON(t, router, http.MethodPost, "/api/auth/login", LoginRequest{Email: user.Email, Password: "correct-password"})
+	challenge, _ := body["challenge_token"].(string)
+	if status != http.StatusOK || challenge == "" || body["token"] != nil {
+		t.Fatalf("login: status %d, %v", status, body)
+	}
+	return challenge
+}
+
+func verifySecondFactor(t *testing.T, router *gin.Engine, input TwoFactorVerifyRequest) (int, map[string]interface{}) {
+	t.Helper()
+	return doJSON(t, router, http.MethodPost, "/api/auth/2fa/verify", input)
+}
+
+func TestTwoFactorLoginWithTOTP(t *testing.T) {
+	router, user := setupTwoFactorTest(t)
+	secret, _ := enrollTwoFactor(t, router)
+
+	// The enrollment code's step is spent, so sign in with the next one, as after 30 seconds
+	code, _ := utils.TOTPCode(secret, time.Now().Add(30*time.Second))
+	status, body := verifySecondFactor(t, router, TwoFactorVerifyRequest{ChallengeToken: passwordStep(t, router, user), Code: code})
+	if status != http.StatusOK {
+		t.Fatalf("verify: status %d, %v", status, body)
+	}
+	claims, err := utils.ValidateToken(body["token"].(string))
+	if err != nil || claims.UserID != user.ID || body["refresh_token"] == "" {
+		t.Fatalf("login response %v: %v", body, err)
+	}
+
+	// A code seen once cannot be replayed, even with a fresh challenge
+	if status, _ := verifySecondFactor(t, router, TwoFactorVerifyRequest{ChallengeToken: passwordStep(t, router, user), Code: code}); status != http.StatusUnauthorized {
+		t.Fatalf("replayed code: status %d, want 401", status)
+	}
+	if status, _ := verifySecondFactor(t, router, TwoFactorVerifyRequest{ChallengeToken: passwordStep(t, router, user), Code: "000000"}); status != http.StatusUnauthorized {
+		t.Fatalf("wrong code: status %d, want 401", status)
+	}
+	if status, _ := verifySecondFactor(t, router, TwoFactorVerifyRequest{ChallengeToken: passwordStep(t, router, user)}); status != http.StatusBadRequest {
+		t.Fatalf("no code: status %d, want 400", status)
+	}
+}
+
+func TestTwoFactorLoginWithRecoveryCode(t *testing.T) {
+	router, user := setupTwoFactorTest(t)
+	_, recovery := enrollTwoFactor(t, router)
+	if len(recovery) != recoveryCodeCount {
+		t.Fatalf("%d recovery codes, want %d", len(recovery), recoveryCodeCount)
+	}
+
+	status, body := verifySecondFactor(t, router, TwoFactorVerifyRequest{ChallengeToken: passwordStep(t, router, user), RecoveryCode: recovery[0]})
+	if status != http.StatusOK || body["token"] == nil {
+		t.Fatalf("recovery code: status %d, %v", status, body)
+	}
+	if status, _ := verifySecondFactor(t, router, TwoFactorVerifyRequest{ChallengeToken: passwordStep(t, router, user), RecoveryCode: recovery[0]}); status != http.StatusUnauthorized {
+		t.Fatalf("reused recovery code: status %d, want 401", status)
+	}
+
+	var remaining int64
+	config.DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).Count(&remaining)
+	if remaining != recoveryCodeCount-1 {
+		t.Fatalf("%d unused recovery codes, want %d", remaining, recoveryCodeCount-1)
+	}
+}
+
+func TestTwoFactorVerifyRejectsBadChallenges(t *testing.T) {
+	router, user := setupTwoFactorTest(t)
+	secret, _ := enrollTwoFactor(t, router)
+	code, _ := utils.TOTPCode(secret, time.Now().Add(30*time.Second))
+
+	access, err := utils.GenerateToken(user.ID, user.Role, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := utils.GeneratePurposeToken(user.ID, "", purposeTwoFactorChallenge, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyLink, err := utils.GeneratePurposeToken(user.ID, user.Email, purposeVerifyEmail, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, challenge := range map[string]string{"access token": access, "expired": expired, "other purpose": verifyLink, "garbage": "x"} {
+		if status, _ := verifySecondFactor(t, router, TwoFactorVerifyRequest{ChallengeToken: challenge, Code: code}); status != http.StatusUnauthorized {
+			t.Errorf("%s challenge: status %d, want 401", name, status)
+		}
+	}
+
+	// None of those consumed the code
+	if status, _ := verifySecondFactor(t, router, TwoFactorVerifyRequest{ChallengeToken: passwordStep(t, router, user), Code: code}); status != http.StatusOK {
+		t.Fatalf("valid challenge: status %d", status)
+	}
+}
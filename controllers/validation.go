@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"regexp"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Usernames appear in URLs and mentions: letters, digits, dots, dashes and underscores,
+// starting with a letter or digit
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// GitHub logins are letters, digits and single dashes, not at either end
+var githubLoginPattern = regexp.MustCompile(`^[A-Za-z0-9]+(-[A-Za-z0-9]+)*$`)
+
+// GitHub repository names are letters, digits, dots, dashes and underscores
+var githubRepoPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Skill names are letters and digits with the punctuation technology names use, e.g. "C++", "C#",
+// ".NET", "Node.js" or "CI/CD"
+var skillNamePattern = regexp.MustCompile(`^[\p{L}\p{N}.][\p{L}\p{N} +#./-]*$`)
+
+// Registers the custom rules used in request binding tags
+func init() {
+	if engine, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		_ = engine.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+			return usernamePattern.MatchString(fl.Field().String())
+		})
+		_ = engine.RegisterValidation("github_login", func(fl validator.FieldLevel) bool {
+			return githubLoginPattern.MatchString(fl.Field().String())
+		})
+		_ = engine.RegisterValidation("github_repo", func(fl validator.FieldLevel) bool {
+			name := fl.Field().String()
+			return githubRepoPattern.MatchString(name) && name != "." && name != ".."
+		})
+		_ = engine.RegisterValidation("skill_name", func(fl validator.FieldLevel) bool {
+			return skillNamePattern.MatchString(fl.Field().String())
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gitconnect-backend/audit"
+	"gitconnect-backend/config"
+	"gitconnect-backend/mailer"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"gitconnect-backend/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	purposeVerifyEmail   = "verify_email"
+	verificationLinkTTL  = 48 * time.Hour
+	defaultPublicBaseURL = "http://localhost:8080"
+)
+
+// publicURL builds an absolute link to this API, using APP_BASE_URL when deployed behind a public hostname
+func publicURL(path string, query url.Values) string {
+	base := strings.TrimRight(os.Getenv("APP_BASE_URL"), "/")
+	if base == "" {
+		base = defaultPublicBaseURL
+	}
+	link := base + path
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+	return link
+}
+
+// sendVerificationEmail mails a signed link that confirms the user's current email address
+func sendVerificationEmail(user *models.User) error {
+	token, err := utils.GeneratePurposeToken(user.ID, user.Email, purposeVerifyEmail, verificationLinkTTL)
+	if err != nil {
+		return err
+	}
+
+	link := publicURL("/api/auth/verify-email", url.Values{"token": {token}})
+	return mailer.Default.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your GitConnect email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\n"+
+			"The link expires in %d hours. If you did not create a GitConnect account, you can ignore this email.\n",
+			user.Username, link, int(verificationLinkTTL.Hours())),
+	})
+}
+
+// claimUnverifiedAccount marks the address verified for whoever just proved control of the mailbox
+// some other way than the registration link. Until then anyone could have registered the address,
+// so the password, 2FA and passkeys set up so far are dropped; once the transaction commits, sign out whoever used them.
+func claimUnverifiedAccount(tx *gorm.DB, user *models.User) error {
+	now := time.Now()
+	if err := tx.Model(user).Updates(map[string]interface{}{
+		"email_verified":    true,
+		"email_verified_at": now,
+		"password":          "",
+		"totp_secret":       "",
+		"totp_enabled":      false,
+		"totp_last_step":    0,
+	}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.WebAuthnCredential{}).Error; err != nil {
+		return err
+	}
+	if err := invalidateEmailChanges(tx, user.ID); err != nil {
+		return err
+	}
+	user.EmailVerified, user.EmailVerifiedAt = true, &now
+	user.Password, user.TOTPSecret, user.TOTPEnabled, user.TOTPLastStep = "", "", false, 0
+	return nil
+}
+
+// revokeClaimedAccount signs out every session and token of an account claimed by
+// claimUnverifiedAccount; method says how the mailbox was proven
+func revokeClaimedAccount(c *gin.Context, user *models.User, method string) error {
+	if err := revokeAllUserTokens(user.ID); err != nil {
+		return err
+	}
+	if err := revokePersonalAccessTokens(user.ID); err != nil {
+		return err
+	}
+	audit.Log(c, models.AuditEmailVerified, user.ID, audit.Details{"email": user.Email, "method": method, "credentials_reset": true})
+	return nil
+}
+
+// @Summary Verify email address
+// @Description Confirms the email address from the signed link sent on registration
+// @Tags Auth
+// @Produce json
+// @Param token query string true "Verification token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/verify-email [get]
+func VerifyEmail(c *gin.Context) {
+	claims, err := utils.ValidatePurposeToken(c.Query("token"), purposeVerifyEmail)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, claims.UserID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+		return
+	}
+
+	// A link issued for an address the user has since changed away from is stale
+	if !strings.EqualFold(user.Email, claims.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+		return
+	}
+
+	if !user.EmailVerified {
+		now := time.Now()
+		if err := config.DB.Model(&user).Updates(map[string]interface{}{
+			"email_verified":    true,
+			"email_verified_at": now,
+		}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+			return
+		}
+		middlewares.ForgetUser(user.ID)
+		audit.Log(c, models.AuditEmailVerified, user.ID, audit.Details{"email": user.Email})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// @Summary Resend verification email
+// @Description Sends a fresh verification link to the current user's email address
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/resend-verification [post]
+func ResendVerification(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	if user.EmailVerified {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already verified"})
+		return
+	}
+
+	if err := sendVerificationEmail(user); err != nil {
+		log.Println("❌ Failed to send verification email:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
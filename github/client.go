@@ -0,0 +1,125 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultWebURL = "https://github.com"
+	defaultAPIURL = "https://api.github.com"
+)
+
+// Client talks to GitHub (or a stand-in such as an httptest server) over its web and REST endpoints
+type Client struct {
+	// WebURL hosts the OAuth endpoints, e.g. https://github.com
+	WebURL string
+	// APIURL hosts the REST API, e.g. https://api.github.com
+	APIURL string
+	// Token, when set, authenticates API calls made without a user token (raises rate limits)
+	Token string
+
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+
+	HTTP *http.Client
+}
+
+// Default is the client used by the controllers; set it once at startup
+var Default = NewClientFromEnv()
+
+// NewClientFromEnv configures a client from GITHUB_* environment variables.
+// GITHUB_BASE_URL and GITHUB_API_URL let tests and GitHub Enterprise point elsewhere.
+func NewClientFromEnv() *Client {
+	webURL := os.Getenv("GITHUB_BASE_URL")
+	if webURL == "" {
+		webURL = defaultWebURL
+	}
+	apiURL := os.Getenv("GITHUB_API_URL")
+	if apiURL == "" {
+		apiURL = defaultAPIURL
+	}
+
+	return &Client{
+		WebURL:       strings.TrimRight(webURL, "/"),
+		APIURL:       strings.TrimRight(apiURL, "/"),
+		Token:        os.Getenv("GITHUB_TOKEN"),
+		ClientID:     os.Getenv("GITHUB_CLIENT_ID"),
+		ClientSecret: os.Getenv("GITHUB_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("GITHUB_REDIRECT_URL"),
+		HTTP:         &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// APIError is a non-2xx response from GitHub
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("github: %d %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from GitHub
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*APIError)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsRateLimited reports whether err means GitHub is throttling us; further calls will fail until the window resets
+func IsRateLimited(err error) bool {
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusTooManyRequests ||
+		(apiErr.StatusCode == http.StatusForbidden && strings.Contains(strings.ToLower(apiErr.Message), "rate limit"))
+}
+
+// getJSON performs an authenticated GET against the REST API and decodes the response into out
+func (c *Client) getJSON(path, token string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.APIURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if token == "" {
+		token = c.Token
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.do(req, out)
+}
+
+func (c *Client) do(req *http.Request, out interface{}) error {
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var body struct {
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(data, &body) != nil || body.Message == "" {
+			body.Message = http.StatusText(resp.StatusCode)
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: body.Message}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,117 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// oauthScopes are the minimum needed to identify the user and read their verified emails
+const oauthScopes = "read:user user:email"
+
+// ErrOAuthNotConfigured is returned when GITHUB_CLIENT_ID / GITHUB_CLIENT_SECRET are missing
+var ErrOAuthNotConfigured = errors.New("github: OAuth client is not configured")
+
+// User is the subset of GET /user that GitConnect uses
+type User struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Bio       string `json:"bio"`
+	AvatarURL string `json:"avatar_url"`
+	HTMLURL   string `json:"html_url"`
+	Followers int    `json:"followers"`
+	Following int    `json:"following"`
+}
+
+// Email is an entry of GET /user/emails
+type Email struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// OAuthConfigured reports whether the client can run the authorization-code flow
+func (c *Client) OAuthConfigured() bool {
+	return c.ClientID != "" && c.ClientSecret != ""
+}
+
+// AuthorizeURL is where the user is sent to approve GitConnect
+func (c *Client) AuthorizeURL(state, redirectURL string) string {
+	query := url.Values{}
+	query.Set("client_id", c.ClientID)
+	query.Set("redirect_uri", redirectURL)
+	query.Set("scope", oauthScopes)
+	query.Set("state", state)
+	query.Set("allow_signup", "true")
+	return c.WebURL + "/login/oauth/authorize?" + query.Encode()
+}
+
+// ExchangeCode trades an authorization code for a user access token
+func (c *Client) ExchangeCode(code, redirectURL string) (string, error) {
+	if !c.OAuthConfigured() {
+		return "", ErrOAuthNotConfigured
+	}
+
+	form := url.Values{}
+	form.Set("client_id", c.ClientID)
+	form.Set("client_secret", c.ClientSecret)
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURL)
+
+	req, err := http.NewRequest(http.MethodPost, c.WebURL+"/login/oauth/access_token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	// GitHub reports a bad code as 200 with an "error" field
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := c.do(req, &body); err != nil {
+		return "", err
+	}
+	if body.Error != "" {
+		return "", &APIError{StatusCode: http.StatusBadRequest, Message: body.Error + ": " + body.ErrorDescription}
+	}
+	if body.AccessToken == "" {
+		return "", errors.New("github: no access token in response")
+	}
+	return body.AccessToken, nil
+}
+
+// GetAuthenticatedUser returns the user the token belongs to
+func (c *Client) GetAuthenticatedUser(token string) (*User, error) {
+	var user User
+	if err := c.getJSON("/user", token, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetVerifiedEmails returns the user's verified addresses, primary first
+func (c *Client) GetVerifiedEmails(token string) ([]string, error) {
+	var emails []Email
+	if err := c.getJSON("/user/emails", token, &emails); err != nil {
+		return nil, err
+	}
+
+	var verified []string
+	for _, e := range emails {
+		if !e.Verified {
+			continue
+		}
+		if e.Primary {
+			verified = append([]string{e.Email}, verified...)
+		} else {
+			verified = append(verified, e.Email)
+		}
+	}
+	return verified, nil
+}
@@ -0,0 +1,182 @@
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Repositories are listed 100 per page; users with more than maxRepoPages pages are cut off
+const (
+	reposPerPage = 100
+	maxRepoPages = 5
+	maxPinned    = 6
+)
+
+// ErrTokenRequired is returned for calls GitHub only answers when authenticated, such as GraphQL
+var ErrTokenRequired = errors.New("github: GITHUB_TOKEN is required for this call")
+
+// Repository is the subset of a REST repository object that GitConnect caches
+type Repository struct {
+	Name            string    `json:"name"`
+	FullName        string    `json:"full_name"`
+	Description     string    `json:"description"`
+	HTMLURL         string    `json:"html_url"`
+	Language        string    `json:"language"`
+	StargazersCount int       `json:"stargazers_count"`
+	ForksCount      int       `json:"forks_count"`
+	Fork            bool      `json:"fork"`
+	Archived        bool      `json:"archived"`
+	PushedAt        time.Time `json:"pushed_at"`
+}
+
+// PinnedRepository is a repository pinned on a user's GitHub profile; it may belong to someone else
+type PinnedRepository struct {
+	Name        string `json:"name"`
+	FullName    string `json:"full_name"`
+	Description string `json:"description"`
+	HTMLURL     string `json:"html_url"`
+	Language    string `json:"language"`
+	Stars       int    `json:"stars"`
+	Forks       int    `json:"forks"`
+}
+
+// PublicUser is GET /users/{login}
+type PublicUser struct {
+	User
+	PublicRepos int `json:"public_repos"`
+}
+
+// GetUser returns a user's public profile
+func (c *Client) GetUser(login string) (*PublicUser, error) {
+	var user PublicUser
+	if err := c.getJSON("/users/"+url.PathEscape(login), "", &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListRepositories returns the public repositories a user owns, most recently pushed first
+func (c *Client) ListRepositories(login string) ([]Repository, error) {
+	var all []Repository
+	for page := 1; page <= maxRepoPages; page++ {
+		var repos []Repository
+		path := fmt.Sprintf("/users/%s/repos?type=owner&sort=pushed&per_page=%d&page=%d", url.PathEscape(login), reposPerPage, page)
+		if err := c.getJSON(path, "", &repos); err != nil {
+			return nil, err
+		}
+		all = append(all, repos...)
+		if len(repos) < reposPerPage {
+			break
+		}
+	}
+	return all, nil
+}
+
+// pinnedQuery asks for the repositories pinned on a profile, which only the GraphQL API exposes
+const pinnedQuery = `query($login: String!, $first: Int!) {
+  user(login: $login) {
+    pinnedItems(first: $first, types: REPOSITORY) {
+      nodes {
+        ... on Repository {
+          name
+          nameWithOwner
+          description
+          url
+          stargazerCount
+          forkCount
+          primaryLanguage { name }
+        }
+      }
+    }
+  }
+}`
+
+// GetPinnedRepositories returns the repositories pinned on a user's profile, in their pinned order.
+// GraphQL rejects anonymous calls, so this needs Token.
+func (c *Client) GetPinnedRepositories(login string) ([]PinnedRepository, error) {
+	if c.Token == "" {
+		return nil, ErrTokenRequired
+	}
+
+	var body struct {
+		Data struct {
+			User *struct {
+				PinnedItems struct {
+					Nodes []struct {
+						Name            string `json:"name"`
+						NameWithOwner   string `json:"nameWithOwner"`
+						Description     string `json:"description"`
+						URL             string `json:"url"`
+						StargazerCount  int    `json:"stargazerCount"`
+						ForkCount       int    `json:"forkCount"`
+						PrimaryLanguage *struct {
+							Name string `json:"name"`
+						} `json:"primaryLanguage"`
+					} `json:"nodes"`
+				} `json:"pinnedItems"`
+			} `json:"user"`
+		} `json:"data"`
+		Errors []struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	variables := map[string]interface{}{"login": login, "first": maxPinned}
+	if err := c.graphQL(pinnedQuery, variables, &body); err != nil {
+		return nil, err
+	}
+	if len(body.Errors) > 0 {
+		if body.Errors[0].Type == "NOT_FOUND" {
+			return nil, &APIError{StatusCode: http.StatusNotFound, Message: body.Errors[0].Message}
+		}
+		return nil, &APIError{StatusCode: http.StatusBadGateway, Message: body.Errors[0].Message}
+	}
+	if body.Data.User == nil {
+		return nil, &APIError{StatusCode: http.StatusNotFound, Message: "user not found"}
+	}
+
+	pinned := make([]PinnedRepository, 0, len(body.Data.User.PinnedItems.Nodes))
+	for _, node := range body.Data.User.PinnedItems.Nodes {
+		repo := PinnedRepository{
+			Name:        node.Name,
+			FullName:    node.NameWithOwner,
+			Description: node.Description,
+			HTMLURL:     node.URL,
+			Stars:       node.StargazerCount,
+			Forks:       node.ForkCount,
+		}
+		if node.PrimaryLanguage != nil {
+			repo.Language = node.PrimaryLanguage.Name
+		}
+		pinned = append(pinned, repo)
+	}
+	return pinned, nil
+}
+
+// graphQLURL is api.github.com/graphql, or /api/graphql on GitHub Enterprise whose REST API lives under /api/v3
+func (c *Client) graphQLURL() string {
+	if base, ok := strings.CutSuffix(c.APIURL, "/api/v3"); ok {
+		return base + "/api/graphql"
+	}
+	return c.APIURL + "/graphql"
+}
+
+func (c *Client) graphQL(query string, variables map[string]interface{}, out interface{}) error {
+	payload, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.graphQLURL(), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	return c.do(req, out)
+}
@@ -0,0 +1,71 @@
+package github
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Gists listed per verification check; the token is expected in a recent one
+const gistsPerPage = 30
+
+// Gist is a public gist; listings leave Content empty, GetGist fills it in
+type Gist struct {
+	ID          string              `json:"id"`
+	Description string              `json:"description"`
+	HTMLURL     string              `json:"html_url"`
+	Public      bool                `json:"public"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+	Files       map[string]GistFile `json:"files"`
+}
+
+// GistFile is one file of a gist; Content is cut short for files over about 1 MB
+type GistFile struct {
+	Filename  string `json:"filename"`
+	Content   string `json:"content"`
+	Truncated bool   `json:"truncated"`
+}
+
+// ListGists returns a user's most recently updated public gists
+func (c *Client) ListGists(login string) ([]Gist, error) {
+	var gists []Gist
+	path := fmt.Sprintf("/users/%s/gists?per_page=%d", url.PathEscape(login), gistsPerPage)
+	if err := c.getJSON(path, "", &gists); err != nil {
+		return nil, err
+	}
+	return gists, nil
+}
+
+// GetGist returns a gist with the content of its files
+func (c *Client) GetGist(id string) (*Gist, error) {
+	var gist Gist
+	if err := c.getJSON("/gists/"+url.PathEscape(id), "", &gist); err != nil {
+		return nil, err
+	}
+	return &gist, nil
+}
+
+// GetFileContent returns a file from the default branch of a public repository
+func (c *Client) GetFileContent(owner, repo, path string) (string, error) {
+	var file struct {
+		Type     string `json:"type"`
+		Encoding string `json:"encoding"`
+		Content  string `json:"content"`
+	}
+	endpoint := "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo) + "/contents/" + url.PathEscape(path)
+	if err := c.getJSON(endpoint, "", &file); err != nil {
+		return "", err
+	}
+	if file.Type != "file" || file.Encoding != "base64" {
+		return "", &APIError{StatusCode: http.StatusNotFound, Message: "not a file"}
+	}
+	// GitHub wraps the base64 content at 60 characters
+	data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
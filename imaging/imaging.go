@@ -0,0 +1,177 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // registers the GIF decoder with image.Decode
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // registers the WebP decoder with image.Decode
+)
+
+// Limits that keep a small file from decoding into an enormous bitmap
+const (
+	MaxPixels    = 25_000_000
+	MaxDimension = 2048 // longest side of the stored original
+	jpegQuality  = 85
+)
+
+var (
+	// ErrUnsupportedImage is returned for data that is not a JPEG, PNG, GIF or WebP image
+	ErrUnsupportedImage = errors.New("unsupported image format")
+	// ErrImageTooLarge is returned for images with more than MaxPixels pixels
+	ErrImageTooLarge = errors.New("image dimensions are too large")
+)
+
+// Formats accepted as input, as reported by image.DecodeConfig
+var supportedFormats = map[string]bool{"jpeg": true, "png": true, "gif": true, "webp": true}
+
+// Rendition is one encoded variant of a processed image
+type Rendition struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+}
+
+// Result is a re-encoded original plus one thumbnail or preview per requested size
+type Result struct {
+	Original   Rendition
+	Thumbnails map[int]Rendition
+}
+
+// Process decodes an uploaded image and re-encodes it, which drops EXIF, GPS and any other
+// metadata. JPEG orientation is applied first so the picture still displays upright. The
+// original is shrunk to fit MaxDimension; each thumbnail is a centred square of the given size,
+// never larger than the image itself. Opaque images are stored as JPEG, others as PNG. Only
+// the first frame of an animated GIF is kept.
+func Process(data []byte, thumbnailSizes []int) (*Result, error) {
+	return process(data, thumbnailSizes, squareThumbnail)
+}
+
+// ProcessPreviews is Process for images shown whole, such as post media: instead of square
+// thumbnails, each preview is the whole image shrunk to fit a square of the given size.
+func ProcessPreviews(data []byte, previewSizes []int) (*Result, error) {
+	return process(data, previewSizes, fit)
+}
+
+func process(data []byte, sizes []int, variant func(image.Image, int) image.Image) (*Result, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || !supportedFormats[format] {
+		return nil, ErrUnsupportedImage
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrUnsupportedImage
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if format == "jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+
+	opaque := isOpaque(img)
+	original, err := encode(fit(img, MaxDimension), opaque)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Original: original, Thumbnails: make(map[int]Rendition, len(sizes))}
+	for _, size := range sizes {
+		thumbnail, err := encode(variant(img, size), opaque)
+		if err != nil {
+			return nil, err
+		}
+		result.Thumbnails[size] = thumbnail
+	}
+	return result, nil
+}
+
+// encode writes img as JPEG when it has no transparency and as PNG otherwise
+func encode(img image.Image, opaque bool) (Rendition, error) {
+	var buf bytes.Buffer
+	rendition := Rendition{Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+	if opaque {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return Rendition{}, fmt.Errorf("encode jpeg: %w", err)
+		}
+		rendition.ContentType, rendition.Ext = "image/jpeg", ".jpg"
+	} else {
+		if err := png.Encode(&buf, img); err != nil {
+			return Rendition{}, fmt.Errorf("encode png: %w", err)
+		}
+		rendition.ContentType, rendition.Ext = "image/png", ".png"
+	}
+	rendition.Data = buf.Bytes()
+	return rendition, nil
+}
+
+// isOpaque reports whether img has no transparent pixels; decoders return types that know this cheaply
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// fit scales img down so its longest side is at most max; smaller images are returned as they are
+func fit(img image.Image, max int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= max && h <= max {
+		return toRGBA(img)
+	}
+	if w >= h {
+		w, h = max, h*max/w
+	} else {
+		w, h = w*max/h, max
+	}
+	return scale(img, bounds, max1(w), max1(h))
+}
+
+// squareThumbnail crops the centred square of img and scales it to size, or to the square's own
+// side when that is smaller
+func squareThumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	x0 := bounds.Min.X + (bounds.Dx()-side)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-side)/2
+	square := image.Rect(x0, y0, x0+side, y0+side)
+	if size > side {
+		size = side
+	}
+	return scale(img, square, size, size)
+}
+
+func scale(img image.Image, src image.Rectangle, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Src, nil)
+	return dst
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	return dst
+}
+
+func max1(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
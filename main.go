@@ -3,10 +3,17 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	_ "gitconnect-backend/docs" // Import Swagger docs
 	"gitconnect-backend/config"
+	"gitconnect-backend/controllers"
+	"gitconnect-backend/github"
+	"gitconnect-backend/mailer"
 	"gitconnect-backend/routes"
+	"gitconnect-backend/storage"
+	"gitconnect-backend/utils"
+	"gitconnect-backend/webauthn"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -36,6 +43,45 @@ func main() {
 	}
 	log.Println("✅ Database connected successfully.")
 
+	if err := utils.LoadSigningKeys(); err != nil {
+		log.Fatalf("❌ Failed to load JWT signing keys: %v", err)
+	}
+
+	mail, err := mailer.FromEnv()
+	if err != nil {
+		log.Fatalf("❌ Failed to configure mailer: %v", err)
+	}
+	mailer.Default = mail
+
+	blobs, err := storage.FromEnv()
+	if err != nil {
+		log.Fatalf("❌ Failed to configure storage: %v", err)
+	}
+	storage.Default = blobs
+
+	// Re-read GITHUB_* and WEBAUTHN_* now that .env has been loaded
+	github.Default = github.NewClientFromEnv()
+	webauthn.Default = webauthn.NewRelyingPartyFromEnv()
+
+	// Bootstrap admins listed in ADMIN_EMAILS (comma-separated)
+	if err := config.SeedAdmins(os.Getenv("ADMIN_EMAILS")); err != nil {
+		log.Fatalf("❌ Failed to seed admins: %v", err)
+	}
+
+	// Canonical skills and their aliases, e.g. "golang" for Go
+	if err := config.SeedSkills(); err != nil {
+		log.Fatalf("❌ Failed to seed skills: %v", err)
+	}
+
+	// Persist token revocations so logouts survive restarts
+	utils.Revocations = utils.NewDBRevocationStore(config.DB)
+
+	// Delete accounts whose deletion grace period has passed
+	go controllers.RunAccountPurger(time.Hour)
+
+	// Refresh GitHub data of profiles that name an account, as it falls due
+	go controllers.RunGithubSync(time.Minute)
+
 	router := gin.New()
 	router.Use(gin.Logger(), gin.Recovery())
 	router.SetTrustedProxies(nil)
@@ -54,6 +100,9 @@ func main() {
 	routes.AuthRoutes(router)
 	routes.PostRoutes(router)
 	routes.ProfileRoutes(router)
+	routes.AdminRoutes(router)
+	routes.SkillRoutes(router)
+	routes.BlockRoutes(router)
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -1,14 +1,26 @@
 package middlewares
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gitconnect-backend/audit"
+	"gitconnect-backend/config"
+	"gitconnect-backend/models"
 	"gitconnect-backend/utils"
+	"gorm.io/gorm"
 )
 
-// AuthMiddleware verifies the JWT token in the request header.
+// authError is why a bearer token was not accepted, as the status and message AuthMiddleware responds with
+type authError struct {
+	status  int
+	message string
+}
+
+// AuthMiddleware verifies the JWT or personal access token in the request header.
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
@@ -19,25 +31,110 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Ensure token is in "Bearer <token>" format
-		parts := strings.Split(tokenString, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format"})
+		if failure := authenticate(c, tokenString); failure != nil {
+			c.JSON(failure.status, gin.H{"error": failure.message})
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
+
+// OptionalAuth identifies the caller on public routes: a valid bearer token fills the same
+// context keys as AuthMiddleware, while a missing or unusable one leaves the request anonymous
+// instead of rejecting it.
+func OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tokenString := c.GetHeader("Authorization"); tokenString != "" {
+			// The context is only filled on success, so a failure simply leaves the caller anonymous
+			_ = authenticate(c, tokenString)
+		}
+		c.Next()
+	}
+}
 
-		// Validate token
-		claims, err := utils.ValidateToken(parts[1])
+// authenticate resolves an Authorization header and stores the caller on the context
+func authenticate(c *gin.Context, tokenString string) *authError {
+	// Ensure token is in "Bearer <token>" format
+	parts := strings.Split(tokenString, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return &authError{http.StatusUnauthorized, "Invalid authorization format"}
+	}
+
+	// Scripts and bots authenticate with personal access tokens instead of JWTs
+	if strings.HasPrefix(parts[1], models.PersonalAccessTokenPrefix) {
+		return authenticatePersonalAccessToken(c, parts[1])
+	}
+
+	// Validate token
+	claims, err := utils.ValidateToken(parts[1])
+	if err != nil {
+		return &authError{http.StatusUnauthorized, "Invalid token"}
+	}
+
+	// Reject tokens revoked by logout / "log out everywhere"
+	revoked, err := utils.Revocations.IsRevoked(claims)
+	if err != nil {
+		return &authError{http.StatusInternalServerError, "Failed to verify token"}
+	}
+	if revoked {
+		audit.Log(c, models.AuditRevokedTokenUsed, claims.UserID, audit.Details{"reason": "revoked"})
+		return &authError{http.StatusUnauthorized, "Token has been revoked"}
+	}
+
+	// Tokens die with their session, so signing a device out takes effect immediately
+	if claims.SessionID != 0 {
+		active, err := sessionActive(claims.SessionID, claims.UserID)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
-			return
+			return &authError{http.StatusInternalServerError, "Failed to verify token"}
+		}
+		if !active {
+			audit.Log(c, models.AuditRevokedTokenUsed, claims.UserID, audit.Details{"reason": "session_signed_out", "session_id": claims.SessionID})
+			return &authError{http.StatusUnauthorized, "Session has been signed out"}
 		}
+	}
 
-		// Pass user ID to the request context
-		c.Set("user_id", claims.UserID)
-		c.Next()
+	// A valid token is not enough once the account is deleted or suspended
+	user, err := loadPrincipal(claims.UserID)
+	if err != nil {
+		return principalError(err)
 	}
+
+	setPrincipal(c, user)
+	c.Set("claims", claims)
+	c.Set("auth_type", AuthTypeSession)
+	return nil
 }
 
+// setPrincipal stores the authenticated user on the request. The role comes from the account
+// rather than the token, so role changes apply without logging in again.
+func setPrincipal(c *gin.Context, user *models.User) {
+	role := user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+	c.Set("user", user)
+	c.Set("user_id", user.ID)
+	c.Set("role", role)
+}
+
+// sessionActive reports whether the session behind an access token is still signed in,
+// and records that it was seen
+func sessionActive(sessionID, userID uint) (bool, error) {
+	var session models.Session
+	err := config.DB.Where("user_id = ?", userID).First(&session, sessionID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !session.IsActive() {
+		return false, nil
+	}
+
+	if now := time.Now(); now.Sub(session.LastSeenAt) > lastUsedGranularity {
+		config.DB.Model(&session).Update("last_seen_at", now)
+	}
+	return true, nil
+}
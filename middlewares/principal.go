@@ -0,0 +1,127 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"gitconnect-backend/config"
+	"gitconnect-backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// How long a loaded user is trusted before it is read again. Changes made through this process
+// call ForgetUser and apply at once; other instances see them within this window.
+const principalCacheTTL = 30 * time.Second
+
+var (
+	errAccountMissing   = errors.New("account no longer exists")
+	errAccountSuspended = errors.New("account is suspended")
+)
+
+type cachedPrincipal struct {
+	user     models.User
+	loadedAt time.Time
+}
+
+// principalCache holds recently loaded users by ID
+type principalCache struct {
+	mu        sync.Mutex
+	users     map[uint]cachedPrincipal
+	lastPurge time.Time
+}
+
+var principals = &principalCache{users: make(map[uint]cachedPrincipal)}
+
+func (p *principalCache) get(userID uint, now time.Time) (models.User, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry, ok := p.users[userID]
+	if !ok || now.Sub(entry.loadedAt) > principalCacheTTL {
+		return models.User{}, false
+	}
+	return entry.user, true
+}
+
+func (p *principalCache) put(user models.User, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Expired entries are dropped now and then so users who went away do not pile up
+	if now.Sub(p.lastPurge) > principalCacheTTL {
+		for id, entry := range p.users {
+			if now.Sub(entry.loadedAt) > principalCacheTTL {
+				delete(p.users, id)
+			}
+		}
+		p.lastPurge = now
+	}
+	p.users[user.ID] = cachedPrincipal{user: user, loadedAt: now}
+}
+
+func (p *principalCache) forget(userID uint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.users, userID)
+}
+
+// ForgetUser drops a user from the principal cache; call it after changing their role,
+// suspension, email or anything else requests are authorised on, or after deleting them
+func ForgetUser(userID uint) {
+	principals.forget(userID)
+}
+
+// loadPrincipal returns the user a token belongs to, refusing deleted and suspended accounts
+func loadPrincipal(userID uint) (*models.User, error) {
+	now := time.Now()
+	user, ok := principals.get(userID, now)
+	if !ok {
+		err := config.DB.First(&user, userID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errAccountMissing
+		}
+		if err != nil {
+			return nil, err
+		}
+		principals.put(user, now)
+	}
+
+	if user.IsSuspended() {
+		return nil, errAccountSuspended
+	}
+	return &user, nil
+}
+
+// principalError turns a loadPrincipal failure into the response AuthMiddleware sends
+func principalError(err error) *authError {
+	switch {
+	case errors.Is(err, errAccountMissing):
+		return &authError{http.StatusUnauthorized, "Account no longer exists"}
+	case errors.Is(err, errAccountSuspended):
+		return &authError{http.StatusForbidden, "This account has been suspended"}
+	default:
+		return &authError{http.StatusInternalServerError, "Failed to verify token"}
+	}
+}
+
+// CurrentUser returns the authenticated user that AuthMiddleware (or OptionalAuth) loaded for
+// this request. The copy is the request's own, but may be up to principalCacheTTL old, so
+// handlers that check or change credentials should read the row again.
+func CurrentUser(c *gin.Context) (*models.User, bool) {
+	value, exists := c.Get("user")
+	if !exists {
+		return nil, false
+	}
+	user, ok := value.(*models.User)
+	return user, ok && user != nil
+}
+
+// CurrentUserID returns the ID of the authenticated user, or false for anonymous requests
+func CurrentUserID(c *gin.Context) (uint, bool) {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return 0, false
+	}
+	return user.ID, true
+}
@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"gitconnect-backend/audit"
+	"gitconnect-backend/models"
+	"gitconnect-backend/policy"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole only lets through users whose role is at least minimum
+// (admin > moderator > user). It must run after AuthMiddleware.
+func RequireRole(minimum string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, exists := c.Get("role")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+
+		if !models.RoleAtLeast(role.(string), minimum) {
+			id, _ := CurrentUserID(c)
+			audit.Log(c, models.AuditAccessDenied, id, audit.Details{"path": c.FullPath(), "method": c.Request.Method, "required_role": minimum})
+			policy.Forbidden(c, "insufficient_role", "Insufficient permissions")
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"gitconnect-backend/policy"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedEmail blocks the request until the authenticated user has confirmed their email.
+// It must run after AuthMiddleware.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := CurrentUser(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+
+		if !user.EmailVerified {
+			policy.Forbidden(c, "email_unverified", "Please verify your email address first")
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Audit event types
+const (
+	AuditLoginSucceeded     = "login.succeeded"
+	AuditLoginFailed        = "login.failed"
+	AuditAccountLocked      = "login.locked"
+	AuditAccountUnlocked    = "login.unlocked"
+	AuditTwoFactorFailed    = "2fa.failed"
+	AuditTwoFactorEnabled   = "2fa.enabled"
+	AuditTwoFactorDisabled  = "2fa.disabled"
+	AuditRecoveryCodesReset = "2fa.recovery_codes_regenerated"
+	AuditPasswordChanged    = "password.changed"
+	AuditPasswordReset      = "password.reset"
+	AuditEmailChanged       = "email.changed"
+	AuditEmailVerified      = "email.verified"
+	AuditLogout             = "token.logout"
+	AuditLogoutAll          = "token.logout_all"
+	AuditRefreshReuse       = "token.refresh_reuse"
+	AuditRevokedTokenUsed   = "token.revoked_used"
+	AuditSessionRevoked     = "session.revoked"
+	AuditTokenCreated       = "pat.created"
+	AuditTokenRevoked       = "pat.revoked"
+	AuditPasskeyAdded       = "passkey.added"
+	AuditPasskeyRemoved     = "passkey.removed"
+	AuditDeletionRequested  = "account.deletion_requested"
+	AuditDeletionCancelled  = "account.deletion_cancelled"
+	AuditAccountPurged      = "account.purged"
+	AuditGithubVerified     = "github.verified"
+	AuditGithubUnverified   = "github.unverified"
+	AuditAccessDenied       = "admin.access_denied"
+	AuditRoleChanged        = "admin.role_changed"
+	AuditUserSuspended      = "admin.user_suspended"
+	AuditUserUnsuspended    = "admin.user_unsuspended"
+	AuditUserDeleted        = "admin.user_deleted"
+	AuditPostRemoved        = "admin.post_removed"
+	AuditCommentRemoved     = "admin.comment_removed"
+)
+
+var errAuditAppendOnly = errors.New("audit events are append-only")
+
+// AuditEvent is one entry in the security audit log. UserID is the account the event is about
+// and ActorID whoever caused it, when they differ (an admin) or are known. Neither is a foreign
+// key, so the trail outlives deleted accounts.
+type AuditEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Type      string    `json:"type" gorm:"not null;index"`
+	UserID    *uint     `json:"user_id" gorm:"index"`
+	ActorID   *uint     `json:"actor_id,omitempty" gorm:"index"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	Details   string    `json:"details,omitempty" gorm:"type:text"` // JSON object
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// BeforeUpdate keeps the log append-only
+func (e *AuditEvent) BeforeUpdate(tx *gorm.DB) error {
+	return errAuditAppendOnly
+}
+
+// BeforeDelete keeps the log append-only
+func (e *AuditEvent) BeforeDelete(tx *gorm.DB) error {
+	return errAuditAppendOnly
+}
@@ -7,8 +7,17 @@ type Comment struct {
 	PostID    uint      `json:"post_id" gorm:"not null;index"` // Foreign key with index
 	UserID    uint      `json:"user_id"`
 	User      User      `json:"user" gorm:"foreignKey:UserID"` // Relation with User
-	Content   string    `json:"content" binding:"required"`
+	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// PolicyKind identifies the resource type for authorization checks
+func (c *Comment) PolicyKind() string {
+	return "comment"
+}
+
+// OwnerID returns the user who owns the comment
+func (c *Comment) OwnerID() uint {
+	return c.UserID
+}
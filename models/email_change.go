@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// EmailChangeToken is a single-use credential mailed to the address a user wants to switch to
+type EmailChangeToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	User      *User      `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	NewEmail  string     `json:"new_email" gorm:"not null"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex"` // SHA-256 of the emailed token
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// IsActive reports whether the token can still be redeemed
+func (t *EmailChangeToken) IsActive(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// GithubProfile caches the public GitHub data of the account named in Profile.Github.
+// It is refreshed in the background; NextSyncAt says when it is due again.
+type GithubProfile struct {
+	ID           uint                     `json:"-" gorm:"primaryKey;autoIncrement"`
+	ProfileID    uint                     `json:"-" gorm:"not null;uniqueIndex"`
+	Login        string                   `json:"login" gorm:"not null"`
+	Name         string                   `json:"name"`
+	AvatarURL    string                   `json:"avatar_url"`
+	HTMLURL      string                   `json:"html_url"`
+	Followers    int                      `json:"followers"`
+	Following    int                      `json:"following"`
+	PublicRepos  int                      `json:"public_repos"`
+	TotalStars   int                      `json:"total_stars"` // Across the owned repositories that are not forks
+	Languages    []GithubLanguage         `json:"languages" gorm:"foreignKey:GithubProfileID;constraint:OnDelete:CASCADE"`
+	Pinned       []GithubPinnedRepository `json:"pinned_repositories" gorm:"foreignKey:GithubProfileID;constraint:OnDelete:CASCADE"`
+	Repositories []GithubRepository       `json:"repositories" gorm:"foreignKey:GithubProfileID;constraint:OnDelete:CASCADE"`
+	SyncedAt     *time.Time               `json:"synced_at"` // Last successful sync
+	NextSyncAt   time.Time                `json:"-" gorm:"not null;index"`
+	Failures     int                      `json:"-"` // Consecutive failed syncs, for backoff
+	LastError    string                   `json:"sync_error,omitempty"`
+	CreatedAt    time.Time                `json:"-"`
+	UpdatedAt    time.Time                `json:"-"`
+}
+
+// GithubRepository is a public repository owned by the GitHub account
+type GithubRepository struct {
+	ID              uint       `json:"-" gorm:"primaryKey;autoIncrement"`
+	GithubProfileID uint       `json:"-" gorm:"not null;index"`
+	Name            string     `json:"name"`
+	FullName        string     `json:"full_name"`
+	Description     string     `json:"description"`
+	HTMLURL         string     `json:"html_url"`
+	Language        string     `json:"language"`
+	Stars           int        `json:"stars"`
+	Forks           int        `json:"forks"`
+	Fork            bool       `json:"fork"`
+	Archived        bool       `json:"archived"`
+	PushedAt        *time.Time `json:"pushed_at"`
+}
+
+// GithubPinnedRepository is a repository pinned on the GitHub profile, which may belong to someone else
+type GithubPinnedRepository struct {
+	ID              uint   `json:"-" gorm:"primaryKey;autoIncrement"`
+	GithubProfileID uint   `json:"-" gorm:"not null;index"`
+	Position        int    `json:"-"`
+	Name            string `json:"name"`
+	FullName        string `json:"full_name"`
+	Description     string `json:"description"`
+	HTMLURL         string `json:"html_url"`
+	Language        string `json:"language"`
+	Stars           int    `json:"stars"`
+	Forks           int    `json:"forks"`
+}
+
+// GithubLanguage counts the owned, non-fork repositories whose primary language is Name
+type GithubLanguage struct {
+	ID              uint   `json:"-" gorm:"primaryKey;autoIncrement"`
+	GithubProfileID uint   `json:"-" gorm:"not null;index"`
+	Name            string `json:"name"`
+	Repositories    int    `json:"repositories"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// GithubVerificationChallenge is a token the profile owner publishes on GitHub, in a public gist or
+// a repository file, to prove they control Login. There is at most one per profile.
+type GithubVerificationChallenge struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ProfileID uint      `json:"profile_id" gorm:"not null;uniqueIndex"`
+	Profile   *Profile  `json:"-" gorm:"foreignKey:ProfileID;constraint:OnDelete:CASCADE"`
+	Login     string    `json:"login" gorm:"not null"`
+	Token     string    `json:"token" gorm:"not null"` // Meant to be published, so stored as is
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"-"`
+}
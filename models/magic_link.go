@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// MagicLinkToken tracks an emailed sign-in link so it can be used only once
+type MagicLinkToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	User      *User      `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex"` // SHA-256 of the signed link token
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// OAuthAccount links a user to an identity at an external provider such as GitHub
+type OAuthAccount struct {
+	ID             uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID         uint      `json:"user_id" gorm:"not null;index"`
+	User           *User     `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Provider       string    `json:"provider" gorm:"not null;uniqueIndex:idx_oauth_provider_subject"`
+	ProviderUserID string    `json:"provider_user_id" gorm:"not null;uniqueIndex:idx_oauth_provider_subject"`
+	Login          string    `json:"login"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
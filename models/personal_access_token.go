@@ -0,0 +1,55 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Scopes a personal access token can be granted
+const (
+	ScopePostsWrite    = "posts:write"
+	ScopeCommentsWrite = "comments:write"
+	ScopeProfileRead   = "profile:read"
+	ScopeProfileWrite  = "profile:write"
+)
+
+// PersonalAccessTokenPrefix marks PATs so they can be told apart from JWTs (and spotted by secret scanners)
+const PersonalAccessTokenPrefix = "gcp_"
+
+// AllScopes lists every grantable scope
+var AllScopes = []string{ScopePostsWrite, ScopeCommentsWrite, ScopeProfileRead, ScopeProfileWrite}
+
+// ValidScope reports whether scope can be granted to a token
+func ValidScope(scope string) bool {
+	for _, s := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// PersonalAccessToken is a long-lived, user-managed credential for scripts and bots
+type PersonalAccessToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	User       *User      `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Name       string     `json:"name" gorm:"not null"`
+	TokenHash  string     `json:"-" gorm:"not null;uniqueIndex"` // SHA-256 of the token, which is only shown once
+	Hint       string     `json:"hint"`                          // Last characters, to recognise the token in a list
+	Scopes     string     `json:"-" gorm:"not null"`             // Space-separated
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ScopeList returns the granted scopes
+func (t *PersonalAccessToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+// IsActive reports whether the token can still authenticate
+func (t *PersonalAccessToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
@@ -4,15 +4,16 @@ import "time"
 
 // Post represents a post in the system
 type Post struct {
-	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	Content   string    `json:"content" binding:"required"`
-	UserID    uint      `json:"user_id" gorm:"not null;index"` // Foreign key for users
-	User      User      `json:"user" gorm:"foreignKey:UserID"` // Establish relation
-	Likes     int       `json:"likes" gorm:"default:0"`
-	Dislikes  int       `json:"dislikes" gorm:"default:0"`
-	Comments  []Comment `json:"comments" gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE;"` // Comments linked to post
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uint        `json:"id" gorm:"primaryKey;autoIncrement"`
+	Content   string      `json:"content"`
+	UserID    uint        `json:"user_id" gorm:"not null;index"` // Foreign key for users
+	User      User        `json:"user" gorm:"foreignKey:UserID"` // Establish relation
+	Likes     int         `json:"likes" gorm:"default:0"`
+	Dislikes  int         `json:"dislikes" gorm:"default:0"`
+	Comments  []Comment   `json:"comments" gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE;"` // Comments linked to post
+	Media     []PostMedia `json:"media" gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`     // Attached images, oldest first
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
 }
 
 // LikePost increments the like count for the post
@@ -25,3 +26,32 @@ func (p *Post) DislikePost() {
 	p.Dislikes++
 }
 
+// AddReaction counts a like or dislike
+func (p *Post) AddReaction(kind string) {
+	switch kind {
+	case ReactionLike:
+		p.LikePost()
+	case ReactionDislike:
+		p.DislikePost()
+	}
+}
+
+// RemoveReaction takes back a like or dislike, never going below zero
+func (p *Post) RemoveReaction(kind string) {
+	switch {
+	case kind == ReactionLike && p.Likes > 0:
+		p.Likes--
+	case kind == ReactionDislike && p.Dislikes > 0:
+		p.Dislikes--
+	}
+}
+
+// PolicyKind identifies the resource type for authorization checks
+func (p *Post) PolicyKind() string {
+	return "post"
+}
+
+// OwnerID returns the user who owns the post
+func (p *Post) OwnerID() uint {
+	return p.UserID
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PostMediaSizes are the previews, in pixels, generated for every post image; each is the whole
+// image fitted into a square of that size
+var PostMediaSizes = []int{256, 512, 1024}
+
+// MaxPostMedia is how many images one post can carry
+const MaxPostMedia = 4
+
+// PostMedia is an image attached to a post
+type PostMedia struct {
+	ID          uint              `json:"id" gorm:"primaryKey;autoIncrement"`
+	PostID      uint              `json:"-" gorm:"not null;index"`
+	Post        *Post             `json:"-" gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+	Key         string            `json:"-" gorm:"not null"` // Blob storage key of the processed original
+	ContentType string            `json:"content_type"`
+	Width       int               `json:"width"`
+	Height      int               `json:"height"`
+	URL         string            `json:"url" gorm:"-"`
+	URLs        map[string]string `json:"urls" gorm:"-"` // "original" and one entry per PostMediaSizes
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// AfterFind fills in where the image is served
+func (m *PostMedia) AfterFind(tx *gorm.DB) error {
+	m.setURL()
+	return nil
+}
+
+// AfterSave fills in where a new image is served
+func (m *PostMedia) AfterSave(tx *gorm.DB) error {
+	m.setURL()
+	return nil
+}
+
+func (m *PostMedia) setURL() {
+	m.URL = fmt.Sprintf("/api/posts/%d/media/%d", m.PostID, m.ID)
+	m.URLs = map[string]string{"original": m.URL}
+	for _, size := range PostMediaSizes {
+		m.URLs[strconv.Itoa(size)] = fmt.Sprintf("%s?size=%d", m.URL, size)
+	}
+}
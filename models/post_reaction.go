@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Kinds of PostReaction
+const (
+	ReactionLike    = "like"
+	ReactionDislike = "dislike"
+)
+
+// PostReaction records whether a user liked or disliked a post, so each user counts once and
+// can be shown their own reaction. The Likes and Dislikes counters on Post stay the source of
+// the totals; reactions given before this table existed are only in the counters.
+type PostReaction struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	PostID    uint      `json:"post_id" gorm:"not null;uniqueIndex:idx_post_reaction_user"`
+	Post      *Post     `json:"-" gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_post_reaction_user;index"`
+	User      *User     `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Kind      string    `json:"kind" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -1,17 +1,73 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ProfilePictureSizes are the square thumbnails, in pixels, generated for every profile picture
+var ProfilePictureSizes = []int{64, 256, 512}
 
 // Profile represents a user's profile
 type Profile struct {
-	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	UserID    uint      `json:"user_id" gorm:"not null;unique;index"`
-	User      *User     `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"` // Prevent circular JSON recursion
-	FullName  string    `json:"full_name" binding:"required"`
-	Bio       string    `json:"bio"`
-	Github    string    `json:"github"`
-  ProfilePicture string   `json:"profile_picture"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                 uint              `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID             uint              `json:"user_id" gorm:"not null;unique;index"`
+	User               *User             `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"` // Prevent circular JSON recursion
+	FullName           string            `json:"full_name"`
+	Bio                string            `json:"bio"`
+	Github             string            `json:"github"`
+	GithubVerified     bool              `json:"github_verified"` // The owner proved they control Github
+	GithubVerifiedAt   *time.Time        `json:"github_verified_at"`
+	GithubUserID       int64             `json:"-"` // GitHub's numeric ID for the verified login; a recreated login loses the badge
+	ProfilePicture     string            `json:"-"` // Blob storage key of the processed original, served at ProfilePictureURL
+	ProfilePictureURL  string            `json:"profile_picture_url,omitempty" gorm:"-"`
+	ProfilePictureURLs map[string]string `json:"profile_picture_urls,omitempty" gorm:"-"`                                          // "original" and one entry per ProfilePictureSizes
+	Skills             []ProfileSkill    `json:"skills,omitempty" gorm:"foreignKey:ProfileID;constraint:OnDelete:CASCADE"`         // Loaded by GetProfile, strongest first
+	GithubProfile      *GithubProfile    `json:"github_profile,omitempty" gorm:"foreignKey:ProfileID;constraint:OnDelete:CASCADE"` // Synced from GitHub, loaded by GetProfile
+	CreatedAt          time.Time         `json:"created_at"`
+	UpdatedAt          time.Time         `json:"updated_at"`
+}
+
+// ClearGithubVerification drops the badge, e.g. when the login changes
+func (p *Profile) ClearGithubVerification() {
+	p.GithubVerified = false
+	p.GithubVerifiedAt = nil
+	p.GithubUserID = 0
 }
 
+// AfterFind fills in where the profile picture is served
+func (p *Profile) AfterFind(tx *gorm.DB) error {
+	p.setPictureURL()
+	return nil
+}
+
+// AfterSave keeps the picture URLs in step after an update
+func (p *Profile) AfterSave(tx *gorm.DB) error {
+	p.setPictureURL()
+	return nil
+}
+
+func (p *Profile) setPictureURL() {
+	p.ProfilePictureURL, p.ProfilePictureURLs = "", nil
+	if p.ProfilePicture == "" {
+		return
+	}
+	p.ProfilePictureURL = fmt.Sprintf("/api/profiles/%d/picture", p.ID)
+	p.ProfilePictureURLs = map[string]string{"original": p.ProfilePictureURL}
+	for _, size := range ProfilePictureSizes {
+		p.ProfilePictureURLs[strconv.Itoa(size)] = fmt.Sprintf("%s?size=%d", p.ProfilePictureURL, size)
+	}
+}
+
+// PolicyKind identifies the resource type for authorization checks
+func (p *Profile) PolicyKind() string {
+	return "profile"
+}
+
+// OwnerID returns the user who owns the profile
+func (p *Profile) OwnerID() uint {
+	return p.UserID
+}
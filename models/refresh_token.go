@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// RefreshToken is a long-lived, single-use credential exchanged for a new access token.
+// Tokens issued from the same login share a FamilyID so a replayed token can revoke the whole chain.
+type RefreshToken struct {
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID       uint       `json:"user_id" gorm:"not null;index"`
+	User         *User      `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	TokenHash    string     `json:"-" gorm:"not null;uniqueIndex"` // SHA-256 of the raw token, never the token itself
+	FamilyID     string     `json:"family_id" gorm:"not null;index"`
+	SessionID    *uint      `json:"session_id" gorm:"index"` // Nil for tokens issued before sessions were tracked
+	ExpiresAt    time.Time  `json:"expires_at"`
+	UsedAt       *time.Time `json:"used_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ReplacedByID *uint      `json:"replaced_by_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// IsActive reports whether the token can still be exchanged
+func (t *RefreshToken) IsActive(now time.Time) bool {
+	return t.UsedAt == nil && t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// RevokedToken records an access token (by its jti) that must no longer be accepted
+type RevokedToken struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	JTI       string    `json:"jti" gorm:"column:jti;not null;uniqueIndex"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"` // Safe to purge once the token itself would have expired
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TokenCutoff invalidates every access token a user was issued before RevokedBefore ("log out everywhere")
+type TokenCutoff struct {
+	UserID        uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	RevokedBefore time.Time `json:"revoked_before"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Session is one successful login on one device. Its refresh tokens and the access tokens
+// minted from them carry its ID, so revoking the session signs that device out immediately.
+type Session struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	User       *User      `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// IsActive reports whether the session has not been signed out
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Proficiency levels, lowest first
+const (
+	LevelBeginner     = "beginner"
+	LevelIntermediate = "intermediate"
+	LevelAdvanced     = "advanced"
+	LevelExpert       = "expert"
+)
+
+// SkillLevels lists the proficiency levels in ascending order
+var SkillLevels = []string{LevelBeginner, LevelIntermediate, LevelAdvanced, LevelExpert}
+
+// LevelsFrom returns the levels at or above min, or nil when min is not a level
+func LevelsFrom(min string) []string {
+	for i, level := range SkillLevels {
+		if level == min {
+			return SkillLevels[i:]
+		}
+	}
+	return nil
+}
+
+// Skill is a canonical technology or tag, e.g. "Go" or "PostgreSQL"
+type Skill struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name      string    `json:"name" gorm:"not null"`             // Display name
+	Slug      string    `json:"slug" gorm:"not null;uniqueIndex"` // SkillSlug of Name, used in URLs
+	CreatedAt time.Time `json:"-"`
+}
+
+// SkillAlias maps another spelling to a skill, e.g. "golang" to Go; Alias is a SkillSlug
+type SkillAlias struct {
+	ID      uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	SkillID uint   `json:"skill_id" gorm:"not null;index"`
+	Skill   *Skill `json:"-" gorm:"foreignKey:SkillID;constraint:OnDelete:CASCADE"`
+	Alias   string `json:"alias" gorm:"not null;uniqueIndex"`
+}
+
+// ProfileSkill links a profile to a skill with the owner's proficiency
+type ProfileSkill struct {
+	ProfileID uint      `json:"-" gorm:"primaryKey"`
+	SkillID   uint      `json:"-" gorm:"primaryKey;index"`
+	Skill     Skill     `json:"skill" gorm:"foreignKey:SkillID;constraint:OnDelete:CASCADE"`
+	Level     string    `json:"level" gorm:"not null"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// SkillSlug normalizes a skill name for matching and URLs: lower case, "+" as "p" and "#" as
+// "sharp" (so C++ is "cpp" and C# is "csharp"), a leading dot as "dot" (.NET is "dotnet"), and
+// any other run of punctuation or spaces as one dash
+func SkillSlug(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	var b strings.Builder
+	if strings.HasPrefix(name, ".") {
+		b.WriteString("dot")
+		name = name[1:]
+	}
+	dash := false
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+			b.WriteRune(r)
+			dash = false
+		case r == '+':
+			b.WriteByte('p')
+			dash = false
+		case r == '#':
+			b.WriteString("sharp")
+			dash = false
+		default:
+			if !dash && b.Len() > 0 {
+				b.WriteByte('-')
+				dash = true
+			}
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
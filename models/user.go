@@ -2,14 +2,62 @@ package models
 
 import "time"
 
+// Roles, in increasing order of privilege
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// roleRank orders roles so "moderator or above" checks are simple comparisons
+var roleRank = map[string]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// RoleAtLeast reports whether role grants at least the privileges of minimum
+func RoleAtLeast(role, minimum string) bool {
+	return roleRank[role] >= roleRank[minimum] && roleRank[minimum] > 0
+}
+
 // User represents a registered user
 type User struct {
 	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
 	Username  string    `json:"username" gorm:"unique;not null"`
 	Email     string    `json:"email" gorm:"unique;not null"`
-	Password  string    `json:"-"` // Exclude password from JSON response
+	Password  string    `json:"-"`                                                                      // Exclude password from JSON response
 	Profile   *Profile  `json:"profile,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"` // Use pointer to avoid recursion
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	Role        string     `json:"role" gorm:"not null;default:'user';index"`
+	SuspendedAt *time.Time `json:"suspended_at,omitempty"`
+
+	EmailVerified   bool       `json:"email_verified" gorm:"not null;default:false"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+
+	// TOTP two-factor state; the secret is pending until TOTPEnabled is set
+	TOTPSecret   string `json:"-"`
+	TOTPEnabled  bool   `json:"-" gorm:"not null;default:false"`
+	TOTPLastStep int64  `json:"-"` // Last accepted time step, blocks code replay
+
+	// Set while a self-service deletion is pending; the account is purged once it passes
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty" gorm:"index"`
 }
 
+// IsSuspended reports whether an admin has suspended the account
+func (u *User) IsSuspended() bool {
+	return u.SuspendedAt != nil
+}
+
+// DeletionPending reports whether the user has asked for their account to be deleted
+func (u *User) DeletionPending() bool {
+	return u.DeletionScheduledAt != nil
+}
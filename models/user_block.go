@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// UserBlock records that one user blocked another. Blocks hide content both ways: neither user
+// sees the other's posts or comments, nor can comment on or react to them.
+type UserBlock struct {
+	BlockerID uint      `json:"-" gorm:"primaryKey"`
+	Blocker   *User     `json:"-" gorm:"foreignKey:BlockerID;constraint:OnDelete:CASCADE"`
+	BlockedID uint      `json:"blocked_id" gorm:"primaryKey;index"`
+	Blocked   *User     `json:"-" gorm:"foreignKey:BlockedID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time `json:"created_at"`
+}
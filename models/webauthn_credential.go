@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// WebAuthnCredential is a passkey or security key registered to a user; a user may have several
+type WebAuthnCredential struct {
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID       uint       `json:"user_id" gorm:"not null;index"`
+	User         *User      `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Name         string     `json:"name"`
+	CredentialID string     `json:"-" gorm:"not null;uniqueIndex"` // base64url, as the browser reports it
+	PublicKey    []byte     `json:"-" gorm:"not null"`             // COSE_Key
+	SignCount    uint32     `json:"-"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Purposes of a WebAuthnChallenge
+const (
+	WebAuthnRegistration = "registration"
+	WebAuthnLogin        = "login"
+)
+
+// WebAuthnChallenge is the server side of one passkey ceremony; it is deleted when used
+type WebAuthnChallenge struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ChallengeHash string    `json:"-" gorm:"not null;uniqueIndex"`
+	Purpose       string    `json:"purpose" gorm:"not null"`
+	UserID        uint      `json:"user_id" gorm:"index"` // Zero for logins, where the passkey identifies the user
+	ExpiresAt     time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,93 @@
+package policy
+
+import (
+	"net/http"
+
+	"gitconnect-backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Action is something a user attempts to do to a resource
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Resource kinds with ownership rules
+const (
+	KindPost    = "post"
+	KindComment = "comment"
+	KindProfile = "profile"
+)
+
+// Resource is anything owned by a single user
+type Resource interface {
+	PolicyKind() string
+	OwnerID() uint
+}
+
+// Actor is the authenticated user attempting an action
+type Actor struct {
+	UserID uint
+	Role   string
+}
+
+// overrides lists the minimum role that may act on resources the actor does not own.
+// Anything not listed is owner-only.
+var overrides = map[string]map[Action]string{
+	KindPost: {
+		ActionUpdate: models.RoleModerator,
+		ActionDelete: models.RoleModerator,
+	},
+	KindComment: {
+		ActionUpdate: models.RoleModerator,
+		ActionDelete: models.RoleModerator,
+	},
+	KindProfile: {
+		ActionCreate: models.RoleAdmin,
+		ActionUpdate: models.RoleModerator,
+		ActionDelete: models.RoleAdmin,
+	},
+}
+
+// Can reports whether actor may perform action on resource: owners always may,
+// others only with a role at or above the override for that resource and action
+func Can(actor Actor, action Action, resource Resource) bool {
+	if actor.UserID == 0 {
+		return false
+	}
+	if resource.OwnerID() == actor.UserID {
+		return true
+	}
+	minimum, ok := overrides[resource.PolicyKind()][action]
+	return ok && models.RoleAtLeast(actor.Role, minimum)
+}
+
+// ActorFromContext builds the actor from what AuthMiddleware stored on the request
+func ActorFromContext(c *gin.Context) Actor {
+	var actor Actor
+	if userID, ok := c.Get("user_id"); ok {
+		actor.UserID, _ = userID.(uint)
+	}
+	if role, ok := c.Get("role"); ok {
+		actor.Role, _ = role.(string)
+	}
+	return actor
+}
+
+// Authorize checks Can for the current request and writes the standard 403 when denied
+func Authorize(c *gin.Context, action Action, resource Resource) bool {
+	if Can(ActorFromContext(c), action, resource) {
+		return true
+	}
+	Forbidden(c, "forbidden", "You do not have permission to "+string(action)+" this "+resource.PolicyKind())
+	return false
+}
+
+// Forbidden writes the 403 body shared by every permission failure and aborts the request
+func Forbidden(c *gin.Context, code, message string) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": message, "code": code})
+}
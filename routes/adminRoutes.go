@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"gitconnect-backend/controllers"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AdminRoutes(router *gin.Engine) {
+	// Moderators and admins
+	moderation := router.Group("/api/admin").Use(middlewares.AuthMiddleware(), middlewares.RequireSession(), middlewares.RequireRole(models.RoleModerator))
+	{
+		moderation.GET("/users", controllers.AdminListUsers)
+		moderation.GET("/users/:id", controllers.AdminGetUser)
+		moderation.POST("/users/:id/unlock", controllers.AdminUnlockUser)
+		moderation.DELETE("/posts/:id", controllers.AdminDeletePost)
+		moderation.DELETE("/comments/:id", controllers.AdminDeleteComment)
+		moderation.POST("/skills/:id/aliases", controllers.AdminAddSkillAlias)
+	}
+
+	// Admins only
+	admin := router.Group("/api/admin").Use(middlewares.AuthMiddleware(), middlewares.RequireSession(), middlewares.RequireRole(models.RoleAdmin))
+	{
+		admin.PUT("/users/:id/role", controllers.AdminUpdateRole)
+		admin.POST("/users/:id/suspend", controllers.AdminSuspendUser)
+		admin.POST("/users/:id/unsuspend", controllers.AdminUnsuspendUser)
+		admin.DELETE("/users/:id", controllers.AdminDeleteUser)
+		admin.GET("/audit", controllers.AdminListAuditEvents)
+	}
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"gitconnect-backend/controllers"
+	"gitconnect-backend/middlewares"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,6 +12,73 @@ func AuthRoutes(router *gin.Engine) {
 	{
 		auth.POST("/register", controllers.Register)
 		auth.POST("/login", controllers.Login)
+		auth.POST("/refresh", controllers.RefreshToken)
+		auth.POST("/logout", middlewares.AuthMiddleware(), controllers.Logout)
+		auth.POST("/logout-all", middlewares.AuthMiddleware(), middlewares.RequireSession(), controllers.LogoutAll)
+		auth.GET("/jwks", controllers.JWKS)
+		auth.GET("/verify-email", controllers.VerifyEmail)
+		auth.POST("/resend-verification", middlewares.AuthMiddleware(), middlewares.RequireSession(), controllers.ResendVerification)
+		auth.POST("/forgot-password", controllers.ForgotPassword)
+		auth.POST("/reset-password", controllers.ResetPassword)
+		auth.GET("/unlock", controllers.UnlockAccount)
+		auth.POST("/magic-link", controllers.RequestMagicLink)
+		auth.POST("/magic-link/verify", controllers.VerifyMagicLink)
+
+		// Changing credentials requires re-authentication
+		auth.PUT("/password", middlewares.AuthMiddleware(), middlewares.RequireSession(), controllers.ChangePassword)
+		auth.PUT("/email", middlewares.AuthMiddleware(), middlewares.RequireSession(), controllers.ChangeEmail)
+		auth.GET("/email/confirm", controllers.ConfirmEmailChange)
+
+		// Recent security events on the caller's own account
+		auth.GET("/activity", middlewares.AuthMiddleware(), middlewares.RequireSession(), controllers.MySecurityActivity)
+
+		// Self-service account deletion, cancellable during the grace period
+		auth.POST("/account/deletion", middlewares.AuthMiddleware(), middlewares.RequireSession(), controllers.RequestAccountDeletion)
+		auth.DELETE("/account/deletion", middlewares.AuthMiddleware(), middlewares.RequireSession(), controllers.CancelAccountDeletion)
+
+		// Sign in with GitHub
+		auth.GET("/github", controllers.GitHubLogin)
+		auth.GET("/github/callback", controllers.GitHubCallback)
+
+		// Passkeys (WebAuthn): login is public, managing passkeys needs a logged-in user
+		auth.POST("/passkeys/login/begin", controllers.BeginPasskeyLogin)
+		auth.POST("/passkeys/login/finish", controllers.FinishPasskeyLogin)
+		passkeys := auth.Group("/passkeys").Use(middlewares.AuthMiddleware(), middlewares.RequireSession())
+		{
+			passkeys.GET("", controllers.ListPasskeys)
+			passkeys.POST("/register/begin", controllers.BeginPasskeyRegistration)
+			passkeys.POST("/register/finish", controllers.FinishPasskeyRegistration)
+			passkeys.DELETE("/:id", controllers.DeletePasskey)
+		}
+
+		// Two-factor authentication
+		auth.POST("/2fa/verify", controllers.VerifyTwoFactor)
+		twoFactor := auth.Group("/2fa").Use(middlewares.AuthMiddleware(), middlewares.RequireSession())
+		{
+			twoFactor.GET("", controllers.TwoFactorStatus)
+			twoFactor.POST("/setup", controllers.SetupTwoFactor)
+			twoFactor.POST("/enable", controllers.EnableTwoFactor)
+			twoFactor.POST("/disable", controllers.DisableTwoFactor)
+			twoFactor.POST("/recovery-codes", controllers.RegenerateRecoveryCodes)
+		}
+
+		// Personal access tokens; only a logged-in user (not another token) can manage them
+		tokens := auth.Group("/tokens").Use(middlewares.AuthMiddleware(), middlewares.RequireSession())
+		{
+			tokens.GET("", controllers.ListPersonalAccessTokens)
+			tokens.POST("", controllers.CreatePersonalAccessToken)
+			tokens.DELETE("/:id", controllers.RevokePersonalAccessToken)
+		}
+
+		// Devices the user is logged in on
+		sessions := auth.Group("/sessions").Use(middlewares.AuthMiddleware(), middlewares.RequireSession())
+		{
+			sessions.GET("", controllers.ListSessions)
+			sessions.DELETE("/:id", controllers.RevokeSession)
+		}
 	}
+
+	// Standard discovery location for other services verifying our tokens
+	router.GET("/.well-known/jwks.json", controllers.JWKS)
 }
 
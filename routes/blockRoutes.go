@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"gitconnect-backend/controllers"
+	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+func BlockRoutes(router *gin.Engine) {
+	// Protected routes: the caller's own blocks
+	blocks := router.Group("/api/blocks").Use(middlewares.AuthMiddleware())
+	{
+		blocks.GET("", middlewares.RequireScope(models.ScopeProfileRead), controllers.ListBlocks)
+		blocks.POST("/:userId", middlewares.RequireScope(models.ScopeProfileWrite), controllers.BlockUser)
+		blocks.DELETE("/:userId", middlewares.RequireScope(models.ScopeProfileWrite), controllers.UnblockUser)
+	}
+}
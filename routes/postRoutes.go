@@ -4,38 +4,50 @@ import (
 	"github.com/gin-gonic/gin"
 	"gitconnect-backend/controllers"
 	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
 )
 
 func PostRoutes(router *gin.Engine) {
-	// Public route: Get all posts
-	router.GET("/api/posts", controllers.GetPosts)
+	// Public route: Get all posts. OptionalAuth adds the caller's own reactions and permissions when logged in.
+	router.GET("/api/posts", middlewares.OptionalAuth(), controllers.GetPosts)
 
 	// Protected routes
 	protected := router.Group("/api/posts").Use(middlewares.AuthMiddleware()) // Updated to use the correct middleware
 	{
 		// Create a new post
-		protected.POST("", controllers.CreatePost)
+		protected.POST("", middlewares.RequireScope(models.ScopePostsWrite), middlewares.RequireVerifiedEmail(), controllers.CreatePost)
 
 		// Update a post
-		protected.PUT("/:id", controllers.UpdatePost)
+		protected.PUT("/:id", middlewares.RequireScope(models.ScopePostsWrite), controllers.UpdatePost)
 
 		// Delete a post
-		protected.DELETE("/:id", controllers.DeletePost)
+		protected.DELETE("/:id", middlewares.RequireScope(models.ScopePostsWrite), controllers.DeletePost)
+
+		// Attach or remove an image
+		protected.POST("/:id/media", middlewares.RequireScope(models.ScopePostsWrite), controllers.UploadPostMedia)
+		protected.DELETE("/:id/media/:mediaId", middlewares.RequireScope(models.ScopePostsWrite), controllers.DeletePostMedia)
 
 		// Like a post
-		protected.POST("/:id/like", controllers.LikePost)
+		protected.POST("/:id/like", middlewares.RequireScope(models.ScopePostsWrite), controllers.LikePost)
 
 		// Dislike a post
-		protected.POST("/:id/dislike", controllers.DislikePost)
+		protected.POST("/:id/dislike", middlewares.RequireScope(models.ScopePostsWrite), controllers.DislikePost)
 
 		// Comment on a post
-		protected.POST("/:id/comments", controllers.CommentOnPost)
+		protected.POST("/:id/comments", middlewares.RequireScope(models.ScopeCommentsWrite), middlewares.RequireVerifiedEmail(), controllers.CommentOnPost)
+
+		// Edit or delete a comment
+		protected.PUT("/:id/comments/:commentId", middlewares.RequireScope(models.ScopeCommentsWrite), controllers.UpdateComment)
+		protected.DELETE("/:id/comments/:commentId", middlewares.RequireScope(models.ScopeCommentsWrite), controllers.DeleteComment)
 	}
 
 	// Get a single post
-	router.GET("/api/posts/:id", controllers.GetPost)
+	router.GET("/api/posts/:id", middlewares.OptionalAuth(), controllers.GetPost)
+
+	// Serve an image attached to a post
+	router.GET("/api/posts/:id/media/:mediaId", middlewares.OptionalAuth(), controllers.GetPostMedia)
 
 	// Get comments for a post
-	router.GET("/api/posts/:id/comments", controllers.GetCommentsForPost)
+	router.GET("/api/posts/:id/comments", middlewares.OptionalAuth(), controllers.GetCommentsForPost)
 }
 
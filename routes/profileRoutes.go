@@ -3,6 +3,7 @@ package routes
 import (
 	"gitconnect-backend/controllers"
 	"gitconnect-backend/middlewares"
+	"gitconnect-backend/models"
 	"github.com/gin-gonic/gin"
 )
 
@@ -10,23 +11,36 @@ func ProfileRoutes(router *gin.Engine) {
 	// Public route: Get all profiles
 	router.GET("/api/profiles", controllers.GetProfiles)
 	
-	// Public route: Serve profile image
-//	router.GET("/api/profiles/:id/image", controllers.GetProfileImage)
+	// Public route: Serve a profile picture
+	router.GET("/api/profiles/:id/picture", controllers.GetProfilePicture)
 
 	// Protected routes
 	protected := router.Group("/api/profiles").Use(middlewares.AuthMiddleware()) // Apply AuthMiddleware to this group
 	{
 		// Create a new profile
-		protected.POST("/", controllers.CreateProfile)
+		protected.POST("/", middlewares.RequireScope(models.ScopeProfileWrite), controllers.CreateProfile)
 
 		// Get a single profile by ID (protected)
-		protected.GET("/:id", controllers.GetProfile)
+		protected.GET("/:id", middlewares.RequireScope(models.ScopeProfileRead), controllers.GetProfile)
 
 		// Update a profile (protected)
-		protected.PUT("/:id", controllers.UpdateProfile)
+		protected.PUT("/:id", middlewares.RequireScope(models.ScopeProfileWrite), controllers.UpdateProfile)
 
-		// Upload a profile image (protected)
-		//protected.POST("/:id/image", controllers.UploadProfileImage)
+		// Delete a profile (protected)
+		protected.DELETE("/:id", middlewares.RequireScope(models.ScopeProfileWrite), controllers.DeleteProfile)
+
+		// Upload or remove a profile picture (protected)
+		protected.POST("/:id/picture", middlewares.RequireScope(models.ScopeProfileWrite), controllers.UploadProfilePicture)
+		protected.DELETE("/:id/picture", middlewares.RequireScope(models.ScopeProfileWrite), controllers.DeleteProfilePicture)
+
+		// Prove ownership of the profile's GitHub login (protected)
+		protected.POST("/:id/github/verification", middlewares.RequireScope(models.ScopeProfileWrite), controllers.StartGithubVerification)
+		protected.POST("/:id/github/verification/check", middlewares.RequireScope(models.ScopeProfileWrite), controllers.CheckGithubVerification)
+
+		// Manage the skills listed on a profile (protected)
+		protected.PUT("/:id/skills", middlewares.RequireScope(models.ScopeProfileWrite), controllers.SetProfileSkills)
+		protected.POST("/:id/skills", middlewares.RequireScope(models.ScopeProfileWrite), controllers.AddProfileSkill)
+		protected.DELETE("/:id/skills/:slug", middlewares.RequireScope(models.ScopeProfileWrite), controllers.RemoveProfileSkill)
 	}
 }
 
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"gitconnect-backend/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func SkillRoutes(router *gin.Engine) {
+	// Public routes: browse skills and find profiles by skill
+	router.GET("/api/skills", controllers.ListSkills)
+	router.GET("/api/skills/:slug/profiles", controllers.GetSkillProfiles)
+}
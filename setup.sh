@@ -34,7 +34,36 @@ DB_USER=postgres
 DB_PASSWORD=yourpassword
 DB_NAME=gitconnect
 DB_PORT=5432
-JWT_SECRET=your_secret_key
+JWT_SECRET=$(openssl rand -hex 32)
+# Comma-separated emails promoted to admin at startup
+ADMIN_EMAILS=
+# Or configure asymmetric keys with kid-based rotation instead of JWT_SECRET:
+# JWT_SIGNING_KEYS=[{"kid":"k1","alg":"EdDSA","private_key_file":"/path/to/ed25519.pem"}]
+# JWT_ACTIVE_KID=k1
+APP_BASE_URL=http://localhost:8080
+FRONTEND_URL=http://localhost:3000
+# Days a self-service account deletion can be cancelled before the purge
+ACCOUNT_DELETION_GRACE_DAYS=14
+# Mail: log (default), file (MAIL_DIR), memory or smtp (SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD)
+MAIL_DRIVER=file
+MAIL_DIR=mail
+MAIL_FROM=GitConnect <no-reply@gitconnect.local>
+# GitHub OAuth app; GITHUB_BASE_URL / GITHUB_API_URL can point at a local stub
+GITHUB_CLIENT_ID=
+GITHUB_CLIENT_SECRET=
+GITHUB_REDIRECT_URL=http://localhost:8080/api/auth/github/callback
+# Profile sync: a token raises the API rate limit and is needed for pinned repositories
+GITHUB_TOKEN=
+GITHUB_SYNC_INTERVAL=6h
+# Passkeys; origins default to FRONTEND_URL and the RP ID to its host
+WEBAUTHN_RP_ID=localhost
+WEBAUTHN_ORIGINS=http://localhost:3000
+# Uploads: local (default, STORAGE_DIR), memory or s3 (S3_BUCKET, S3_REGION, S3_ACCESS_KEY_ID,
+# S3_SECRET_ACCESS_KEY; S3_ENDPOINT for MinIO and other S3-compatible services)
+STORAGE_DRIVER=local
+STORAGE_DIR=uploads
+PROFILE_PICTURE_MAX_BYTES=5242880
+POST_MEDIA_MAX_BYTES=10485760
 EOT
 
 echo "✅ Setup complete! Ready to code. 🚀"
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Keys that could escape the store, or mean different things on disk and in a bucket
+var unsafeKeys = []string{
+	"",
+	".",
+	"..",
+	"../secret.png",
+	"a/../../secret.png",
+	"a/./b.png",
+	"a/..",
+	"/etc/passwd",
+	"a//b.png",
+	"a/",
+	`..\secret.png`,
+	`a\b.png`,
+	"C:/secret.png",
+	"a/b c.png",
+	"a/%2e%2e/b.png",
+	"a\x00.png",
+	"ä.png",
+	strings.Repeat("a", 1025),
+}
+
+func TestValidateKey(t *testing.T) {
+	for _, key := range []string{"a.png", "profile-pictures/1/abc_def.256.jpg", "post-media/12/x.webp", "..a.png", "a..b/c"} {
+		if err := ValidateKey(key); err != nil {
+			t.Errorf("ValidateKey(%q) = %v", key, err)
+		}
+	}
+	for _, key := range unsafeKeys {
+		if err := ValidateKey(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("ValidateKey(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestLocalStoreRejectsPathTraversal(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewLocalStore(filepath.Join(root, "uploads"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A file beside the store that traversal would reach
+	secret := filepath.Join(root, "secret.png")
+	if err := os.WriteFile(secret, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, key := range unsafeKeys {
+		if err := store.Put(ctx, key, []byte("overwritten"), "image/png"); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) = %v, want ErrInvalidKey", key, err)
+		}
+		if _, err := store.Get(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Get(%q) = %v, want ErrInvalidKey", key, err)
+		}
+		if err := store.Delete(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Delete(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+
+	if data, err := os.ReadFile(secret); err != nil || string(data) != "secret" {
+		t.Fatalf("file outside the store changed: %q, %v", data, err)
+	}
+	entries, _ := os.ReadDir(root)
+	if len(entries) != 2 {
+		t.Fatalf("unexpected files beside the store: %v", entries)
+	}
+}
+
+func TestLocalStorePutGetDelete(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	key := "profile-pictures/1/abc.png"
+
+	if err := store.Put(ctx, key, []byte("png bytes"), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "profile-pictures", "1", "abc.png")); err != nil {
+		t.Fatalf("file not written under the store: %v", err)
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(dir, "profile-pictures", "1", ".upload-*"))
+	if len(leftovers) != 0 {
+		t.Fatalf("temporary files left behind: %v", leftovers)
+	}
+
+	object, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(object.Body)
+	object.Body.Close()
+	if string(data) != "png bytes" || object.ContentType != "image/png" || object.Size != int64(len(data)) {
+		t.Fatalf("Get = %q, %s, %d", data, object.ContentType, object.Size)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("second Delete: %v", err)
+	}
+}
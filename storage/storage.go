@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ErrNotFound is returned by Get when nothing is stored under the key
+var ErrNotFound = errors.New("blob not found")
+
+// ErrInvalidKey is returned for keys that could escape the store or are not portable
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// Keys are slash-separated paths of portable characters, the same on disk and in a bucket
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+(/[A-Za-z0-9._-]+)*$`)
+
+// Object is a stored blob; the caller must close Body
+type Object struct {
+	Body        io.ReadCloser
+	ContentType string
+	Size        int64
+}
+
+// BlobStore keeps uploaded files such as profile pictures
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (*Object, error)
+	// Delete removes the blob; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// Default is the store used by the controllers; set it once at startup
+var Default BlobStore = NewMemoryStore()
+
+// ValidateKey rejects keys with empty, "." or ".." segments or characters outside [A-Za-z0-9._-/]
+func ValidateKey(key string) error {
+	if len(key) > 1024 || !keyPattern.MatchString(key) {
+		return ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "." || segment == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
+
+// FromEnv builds a store from STORAGE_DRIVER: "local" (the default), "s3" or "memory"
+func FromEnv() (BlobStore, error) {
+	switch driver := strings.ToLower(os.Getenv("STORAGE_DRIVER")); driver {
+	case "", "local":
+		dir := os.Getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		return NewLocalStore(dir)
+	case "memory":
+		return NewMemoryStore(), nil
+	case "s3":
+		return NewS3StoreFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER %q", driver)
+	}
+}
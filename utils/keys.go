@@ -0,0 +1,351 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sync"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Minimum HMAC secret length; anything shorter is brute-forceable
+const minHMACSecretLength = 32
+
+// KeyConfig describes one signing/verification key as read from configuration.
+//
+// JWT_SIGNING_KEYS (or the file named by JWT_SIGNING_KEYS_FILE) holds a JSON array of these, e.g.
+//
+//	[{"kid":"2025-06","alg":"EdDSA","private_key_file":"/run/secrets/jwt-ed25519.pem"},
+//	 {"kid":"2024-12","alg":"RS256","public_key_file":"/run/secrets/jwt-old.pub"}]
+//
+// JWT_ACTIVE_KID names the key used to sign new tokens; every other key only verifies.
+// Entries with just a public key are verify-only, which is how a retired key is kept
+// around until the tokens it signed have expired.
+type KeyConfig struct {
+	ID             string `json:"kid"`
+	Algorithm      string `json:"alg"`
+	Secret         string `json:"secret,omitempty"`
+	PrivateKey     string `json:"private_key,omitempty"`
+	PrivateKeyFile string `json:"private_key_file,omitempty"`
+	PublicKey      string `json:"public_key,omitempty"`
+	PublicKeyFile  string `json:"public_key_file,omitempty"`
+}
+
+// SigningKey is a parsed key ready for use with jwt-go
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{} // nil when the key is verify-only
+	verifyKey interface{}
+}
+
+// CanSign reports whether the key holds private material
+func (k *SigningKey) CanSign() bool {
+	return k.signKey != nil
+}
+
+// KeySet is the collection of keys tokens are signed and verified with
+type KeySet struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+	order  []string
+}
+
+var (
+	keySetMu sync.RWMutex
+	keySet   *KeySet
+)
+
+var errNoSigningKeys = errors.New("JWT signing keys have not been loaded")
+
+// NewKeySet parses key configs and selects the active signing key
+func NewKeySet(configs []KeyConfig, activeKID string) (*KeySet, error) {
+	if len(configs) == 0 {
+		return nil, errors.New("no JWT keys configured")
+	}
+
+	set := &KeySet{keys: make(map[string]*SigningKey)}
+	for _, cfg := range configs {
+		key, err := parseKeyConfig(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("JWT key %q: %w", cfg.ID, err)
+		}
+		if _, dup := set.keys[key.ID]; dup {
+			return nil, fmt.Errorf("duplicate JWT key id %q", key.ID)
+		}
+		set.keys[key.ID] = key
+		set.order = append(set.order, key.ID)
+	}
+
+	if activeKID == "" {
+		activeKID = set.order[0]
+	}
+	active, ok := set.keys[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("active JWT key %q is not configured", activeKID)
+	}
+	if !active.CanSign() {
+		return nil, fmt.Errorf("active JWT key %q has no private key", activeKID)
+	}
+	set.active = active
+	return set, nil
+}
+
+// SetKeySet installs the keys used by GenerateToken and ValidateToken
+func SetKeySet(set *KeySet) {
+	keySetMu.Lock()
+	defer keySetMu.Unlock()
+	keySet = set
+}
+
+func currentKeySet() (*KeySet, error) {
+	keySetMu.RLock()
+	defer keySetMu.RUnlock()
+	if keySet == nil {
+		return nil, errNoSigningKeys
+	}
+	return keySet, nil
+}
+
+// LoadSigningKeys reads the key set from the environment.
+//
+// Precedence: JWT_SIGNING_KEYS / JWT_SIGNING_KEYS_FILE, then a single HS256 key from JWT_SECRET.
+// Outside release mode a random throwaway secret is generated so local development works
+// without configuration; tokens then do not survive a restart.
+func LoadSigningKeys() error {
+	configs, err := keyConfigsFromEnv()
+	if err != nil {
+		return err
+	}
+
+	if len(configs) == 0 {
+		if os.Getenv("GIN_MODE") == "release" {
+			return errors.New("no JWT keys configured: set JWT_SIGNING_KEYS or JWT_SECRET")
+		}
+		secret, err := GenerateOpaqueToken(minHMACSecretLength)
+		if err != nil {
+			return err
+		}
+		log.Println("⚠️ No JWT keys configured; using an ephemeral development secret")
+		configs = []KeyConfig{{ID: "dev", Algorithm: "HS256", Secret: secret}}
+	}
+
+	set, err := NewKeySet(configs, os.Getenv("JWT_ACTIVE_KID"))
+	if err != nil {
+		return err
+	}
+	SetKeySet(set)
+	return nil
+}
+
+func keyConfigsFromEnv() ([]KeyConfig, error) {
+	raw := os.Getenv("JWT_SIGNING_KEYS")
+	if path := os.Getenv("JWT_SIGNING_KEYS_FILE"); raw == "" && path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT_SIGNING_KEYS_FILE: %w", err)
+		}
+		raw = string(data)
+	}
+
+	if raw != "" {
+		var configs []KeyConfig
+		if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+			return nil, fmt.Errorf("invalid JWT_SIGNING_KEYS: %w", err)
+		}
+		return configs, nil
+	}
+
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		return []KeyConfig{{ID: "default", Algorithm: "HS256", Secret: secret}}, nil
+	}
+	return nil, nil
+}
+
+func parseKeyConfig(cfg KeyConfig) (*SigningKey, error) {
+	if cfg.ID == "" {
+		return nil, errors.New("kid is required")
+	}
+
+	method := jwt.GetSigningMethod(cfg.Algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported algorithm %q", cfg.Algorithm)
+	}
+	key := &SigningKey{ID: cfg.ID, Method: method}
+
+	switch method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if len(cfg.Secret) < minHMACSecretLength {
+			return nil, fmt.Errorf("HMAC secret must be at least %d bytes", minHMACSecretLength)
+		}
+		key.signKey = []byte(cfg.Secret)
+		key.verifyKey = []byte(cfg.Secret)
+		return key, nil
+	case *jwt.SigningMethodRSA, *signingMethodEdDSA:
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", cfg.Algorithm)
+	}
+
+	privatePEM, err := pemFromConfig(cfg.PrivateKey, cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	publicPEM, err := pemFromConfig(cfg.PublicKey, cfg.PublicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case privatePEM != nil:
+		private, err := parsePrivateKey(privatePEM)
+		if err != nil {
+			return nil, err
+		}
+		key.signKey = private
+		switch k := private.(type) {
+		case *rsa.PrivateKey:
+			key.verifyKey = &k.PublicKey
+		case ed25519.PrivateKey:
+			key.verifyKey = k.Public().(ed25519.PublicKey)
+		}
+	case publicPEM != nil:
+		public, err := parsePublicKey(publicPEM)
+		if err != nil {
+			return nil, err
+		}
+		key.verifyKey = public
+	default:
+		return nil, errors.New("a private or public key is required")
+	}
+
+	// The algorithm must match the key type, otherwise an RS256 key could be fed to EdDSA
+	switch key.verifyKey.(type) {
+	case *rsa.PublicKey:
+		if _, ok := method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("RSA key cannot be used with %s", cfg.Algorithm)
+		}
+	case ed25519.PublicKey:
+		if method != SigningMethodEdDSA {
+			return nil, fmt.Errorf("Ed25519 key cannot be used with %s", cfg.Algorithm)
+		}
+	}
+	return key, nil
+}
+
+func pemFromConfig(inline, path string) (*pem.Block, error) {
+	data := []byte(inline)
+	if inline == "" && path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("key is not PEM encoded")
+	}
+	return block, nil
+}
+
+func parsePrivateKey(block *pem.Block) (interface{}, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	switch key.(type) {
+	case *rsa.PrivateKey, ed25519.PrivateKey:
+		return key, nil
+	}
+	return nil, errors.New("private key must be RSA or Ed25519")
+}
+
+func parsePublicKey(block *pem.Block) (interface{}, error) {
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	}
+	return nil, errors.New("public key must be RSA or Ed25519")
+}
+
+// lookupKey resolves the verification key for a parsed token from its kid header
+func (s *KeySet) lookupKey(token *jwt.Token) (interface{}, error) {
+	key := s.active
+	if kid, ok := token.Header["kid"].(string); ok {
+		if key, ok = s.keys[kid]; !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+	}
+	// Never let the token pick the algorithm
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	return key.verifyKey, nil
+}
+
+// JWK is a single JSON Web Key as published in a JWKS document
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JWKS returns the public halves of all asymmetric keys. HMAC secrets are never published.
+func JWKS() ([]JWK, error) {
+	set, err := currentKeySet()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []JWK{}
+	for _, kid := range set.order {
+		key := set.keys[kid]
+		switch public := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Method.Alg(),
+				N:         base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Method.Alg(),
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+	return keys, nil
+}
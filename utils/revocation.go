@@ -0,0 +1,230 @@
+package utils
+
+import (
+	"sync"
+	"time"
+
+	"gitconnect-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevocationStore decides whether an otherwise valid access token has been revoked
+type RevocationStore interface {
+	// RevokeToken blacklists a single access token until it would have expired anyway
+	RevokeToken(jti string, userID uint, expiresAt time.Time) error
+	// RevokeAllForUser invalidates every access token issued to the user up to now
+	RevokeAllForUser(userID uint) error
+	// IsRevoked reports whether the token described by claims has been revoked
+	IsRevoked(claims *Claims) (bool, error)
+}
+
+// Revocations is the store consulted by AuthMiddleware; set it once at startup
+var Revocations RevocationStore = NewMemoryRevocationStore()
+
+// How long a "not revoked" answer from the database is trusted before asking again.
+// Revocations made by this instance take effect immediately; ones made by other
+// instances become visible within this window.
+const revocationCacheTTL = 30 * time.Second
+
+type cachedCutoff struct {
+	revokedBefore time.Time
+	checkedAt     time.Time
+}
+
+// MemoryRevocationStore keeps revocations in process memory only
+type MemoryRevocationStore struct {
+	mu      sync.RWMutex
+	jtis    map[string]time.Time // jti -> token expiry
+	cutoffs map[uint]time.Time   // user ID -> revoked before
+}
+
+// NewMemoryRevocationStore returns an empty in-memory store
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		jtis:    make(map[string]time.Time),
+		cutoffs: make(map[uint]time.Time),
+	}
+}
+
+func (s *MemoryRevocationStore) RevokeToken(jti string, userID uint, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jtis[jti] = expiresAt
+	return nil
+}
+
+func (s *MemoryRevocationStore) RevokeAllForUser(userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cutoffs[userID] = time.Now()
+	return nil
+}
+
+func (s *MemoryRevocationStore) IsRevoked(claims *Claims) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.jtis[claims.Id]; ok {
+		return true, nil
+	}
+	if cutoff, ok := s.cutoffs[claims.UserID]; ok && issuedBefore(claims, cutoff) {
+		return true, nil
+	}
+	return false, nil
+}
+
+// DBRevocationStore persists revocations in the database and caches lookups in memory
+type DBRevocationStore struct {
+	db *gorm.DB
+
+	mu        sync.Mutex
+	revoked   map[string]time.Time // jti -> token expiry, known revoked
+	clean     map[string]time.Time // jti -> when it was last confirmed not revoked
+	cutoffs   map[uint]cachedCutoff
+	lastPurge time.Time
+}
+
+// NewDBRevocationStore returns a store backed by the revoked_tokens and token_cutoffs tables
+func NewDBRevocationStore(db *gorm.DB) *DBRevocationStore {
+	return &DBRevocationStore{
+		db:      db,
+		revoked: make(map[string]time.Time),
+		clean:   make(map[string]time.Time),
+		cutoffs: make(map[uint]cachedCutoff),
+	}
+}
+
+func (s *DBRevocationStore) RevokeToken(jti string, userID uint, expiresAt time.Time) error {
+	record := models.RevokedToken{JTI: jti, UserID: userID, ExpiresAt: expiresAt}
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error; err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revoked[jti] = expiresAt
+	delete(s.clean, jti)
+	return nil
+}
+
+func (s *DBRevocationStore) RevokeAllForUser(userID uint) error {
+	now := time.Now()
+	cutoff := models.TokenCutoff{UserID: userID, RevokedBefore: now}
+	err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_before", "updated_at"}),
+	}).Create(&cutoff).Error
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cutoffs[userID] = cachedCutoff{revokedBefore: now, checkedAt: now}
+	return nil
+}
+
+func (s *DBRevocationStore) IsRevoked(claims *Claims) (bool, error) {
+	now := time.Now()
+
+	revoked, err := s.isJTIRevoked(claims.Id, now)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	cutoff, err := s.cutoffFor(claims.UserID, now)
+	if err != nil {
+		return false, err
+	}
+	return issuedBefore(claims, cutoff), nil
+}
+
+func (s *DBRevocationStore) isJTIRevoked(jti string, now time.Time) (bool, error) {
+	if jti == "" {
+		return false, nil
+	}
+
+	s.mu.Lock()
+	s.purgeLocked(now)
+	if _, ok := s.revoked[jti]; ok {
+		s.mu.Unlock()
+		return true, nil
+	}
+	if checked, ok := s.clean[jti]; ok && now.Sub(checked) < revocationCacheTTL {
+		s.mu.Unlock()
+		return false, nil
+	}
+	s.mu.Unlock()
+
+	var record models.RevokedToken
+	err := s.db.Where("jti = ?", jti).Limit(1).Find(&record).Error
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record.ID != 0 {
+		s.revoked[jti] = record.ExpiresAt
+		return true, nil
+	}
+	s.clean[jti] = now
+	return false, nil
+}
+
+func (s *DBRevocationStore) cutoffFor(userID uint, now time.Time) (time.Time, error) {
+	s.mu.Lock()
+	if cached, ok := s.cutoffs[userID]; ok && now.Sub(cached.checkedAt) < revocationCacheTTL {
+		s.mu.Unlock()
+		return cached.revokedBefore, nil
+	}
+	s.mu.Unlock()
+
+	var record models.TokenCutoff
+	if err := s.db.Where("user_id = ?", userID).Limit(1).Find(&record).Error; err != nil {
+		return time.Time{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cutoffs[userID] = cachedCutoff{revokedBefore: record.RevokedBefore, checkedAt: now}
+	return record.RevokedBefore, nil
+}
+
+// purgeLocked drops cache entries for tokens that have expired; callers must hold s.mu
+func (s *DBRevocationStore) purgeLocked(now time.Time) {
+	if now.Sub(s.lastPurge) < time.Minute {
+		return
+	}
+	s.lastPurge = now
+	for jti, expiresAt := range s.revoked {
+		if now.After(expiresAt) {
+			delete(s.revoked, jti)
+		}
+	}
+	for jti, checked := range s.clean {
+		if now.Sub(checked) >= revocationCacheTTL {
+			delete(s.clean, jti)
+		}
+	}
+	for userID, cached := range s.cutoffs {
+		if now.Sub(cached.checkedAt) >= revocationCacheTTL {
+			delete(s.cutoffs, userID)
+		}
+	}
+}
+
+// PurgeExpired deletes revocation rows for tokens that can no longer be used anyway
+func (s *DBRevocationStore) PurgeExpired() error {
+	return s.db.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error
+}
+
+// issuedBefore reports whether the token was issued before the cutoff.
+// iat only has second precision; comparing strictly keeps a login made right after
+// "log out everywhere" usable, at the cost of sparing tokens minted in that same second.
+func issuedBefore(claims *Claims, cutoff time.Time) bool {
+	if cutoff.IsZero() {
+		return false
+	}
+	return claims.IssuedAt < cutoff.Unix()
+}
@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// AttemptState is what the throttle remembers about one key (an account, an IP, ...)
+type AttemptState struct {
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+// AttemptStore persists failed-attempt counters. The in-memory implementation is enough for a
+// single instance; a shared store (database, Redis) can implement the same interface.
+type AttemptStore interface {
+	Get(key string) (AttemptState, error)
+	Put(key string, state AttemptState) error
+	Delete(key string) error
+}
+
+// ThrottlePolicy configures backoff and lockout for one class of key
+type ThrottlePolicy struct {
+	// FreeAttempts failures are allowed before any delay is imposed
+	FreeAttempts int
+	// BaseDelay doubles with every failure past FreeAttempts, up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// LockoutAfter failures lock the key for LockoutDuration; zero disables lockout
+	LockoutAfter    int
+	LockoutDuration time.Duration
+	// ResetAfter is how long without failures before the counter is forgotten
+	ResetAfter time.Duration
+}
+
+// ThrottleDecision says whether an attempt may proceed and, if not, why and for how long
+type ThrottleDecision struct {
+	Allowed    bool
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+// Throttle applies a policy to counters held in a store
+type Throttle struct {
+	Store  AttemptStore
+	Policy ThrottlePolicy
+	now    func() time.Time
+}
+
+// NewThrottle returns a throttle applying policy to store
+func NewThrottle(store AttemptStore, policy ThrottlePolicy) *Throttle {
+	return &Throttle{Store: store, Policy: policy, now: time.Now}
+}
+
+// Check decides whether another attempt for key is allowed right now
+func (t *Throttle) Check(key string) (ThrottleDecision, error) {
+	state, err := t.load(key)
+	if err != nil {
+		return ThrottleDecision{}, err
+	}
+	now := t.now()
+
+	if now.Before(state.LockedUntil) {
+		return ThrottleDecision{Locked: true, RetryAfter: state.LockedUntil.Sub(now)}, nil
+	}
+	if next := state.LastFailure.Add(t.delay(state.Failures)); now.Before(next) {
+		return ThrottleDecision{RetryAfter: next.Sub(now)}, nil
+	}
+	return ThrottleDecision{Allowed: true}, nil
+}
+
+// Fail records a failed attempt and reports whether it just locked the key
+func (t *Throttle) Fail(key string) (bool, error) {
+	state, err := t.load(key)
+	if err != nil {
+		return false, err
+	}
+	now := t.now()
+
+	state.Failures++
+	state.LastFailure = now
+	locked := false
+	if t.Policy.LockoutAfter > 0 && state.Failures >= t.Policy.LockoutAfter && !now.Before(state.LockedUntil) {
+		state.LockedUntil = now.Add(t.Policy.LockoutDuration)
+		locked = true
+	}
+	return locked, t.Store.Put(key, state)
+}
+
+// Reset clears the counter, after a successful attempt or an explicit unlock
+func (t *Throttle) Reset(key string) error {
+	return t.Store.Delete(key)
+}
+
+// load returns the state for key, forgetting it once it has gone stale
+func (t *Throttle) load(key string) (AttemptState, error) {
+	state, err := t.Store.Get(key)
+	if err != nil {
+		return AttemptState{}, err
+	}
+	now := t.now()
+	if state.Failures > 0 && t.Policy.ResetAfter > 0 &&
+		now.Sub(state.LastFailure) > t.Policy.ResetAfter && !now.Before(state.LockedUntil) {
+		return AttemptState{}, nil
+	}
+	return state, nil
+}
+
+// delay is the wait imposed after the given number of failures
+func (t *Throttle) delay(failures int) time.Duration {
+	over := failures - t.Policy.FreeAttempts
+	if over <= 0 || t.Policy.BaseDelay <= 0 {
+		return 0
+	}
+	delay := time.Duration(float64(t.Policy.BaseDelay) * math.Pow(2, float64(over-1)))
+	if t.Policy.MaxDelay > 0 && (delay > t.Policy.MaxDelay || delay <= 0) {
+		delay = t.Policy.MaxDelay
+	}
+	return delay
+}
+
+// MemoryAttemptStore keeps counters in process memory
+type MemoryAttemptStore struct {
+	mu       sync.Mutex
+	entries  map[string]AttemptState
+	ttl      time.Duration
+	lastScan time.Time
+}
+
+// NewMemoryAttemptStore returns a store that drops entries untouched for longer than ttl
+func NewMemoryAttemptStore(ttl time.Duration) *MemoryAttemptStore {
+	return &MemoryAttemptStore{entries: make(map[string]AttemptState), ttl: ttl}
+}
+
+func (s *MemoryAttemptStore) Get(key string) (AttemptState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries[key], nil
+}
+
+func (s *MemoryAttemptStore) Put(key string, state AttemptState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = state
+	s.evictLocked(time.Now())
+	return nil
+}
+
+func (s *MemoryAttemptStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// evictLocked bounds memory use by dropping stale entries at most once a minute; callers hold s.mu
+func (s *MemoryAttemptStore) evictLocked(now time.Time) {
+	if s.ttl <= 0 || now.Sub(s.lastScan) < time.Minute {
+		return
+	}
+	s.lastScan = now
+	for key, state := range s.entries {
+		if now.Sub(state.LastFailure) > s.ttl && now.After(state.LockedUntil) {
+			delete(s.entries, key)
+		}
+	}
+}
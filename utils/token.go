@@ -1,47 +1,172 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 )
 
-// JWT Secret Key
-var jwtSecret = []byte("your_secret_key")
+// Default lifetimes, overridable with ACCESS_TOKEN_TTL / REFRESH_TOKEN_TTL (Go duration strings)
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
 
 // Claims struct
 type Claims struct {
-	UserID uint `json:"user_id"`
+	UserID uint   `json:"user_id"`
+	Role   string `json:"role,omitempty"`
+	// SessionID ties an access token to the login it came from, so revoking the session revokes it
+	SessionID uint `json:"sid,omitempty"`
+	// Purpose is empty for access tokens and set for single-purpose links (e.g. email verification)
+	Purpose string `json:"purpose,omitempty"`
+	// Email binds a purpose token to the address it was issued for
+	Email string `json:"email,omitempty"`
 	jwt.StandardClaims
 }
 
-// GenerateToken - creates a new JWT token
-func GenerateToken(userID uint) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+var errWrongTokenPurpose = errors.New("token was issued for a different purpose")
+
+// AccessTokenTTL returns how long an access token stays valid
+func AccessTokenTTL() time.Duration {
+	return durationFromEnv("ACCESS_TOKEN_TTL", defaultAccessTokenTTL)
+}
+
+// RefreshTokenTTL returns how long a refresh token stays valid
+func RefreshTokenTTL() time.Duration {
+	return durationFromEnv("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL)
+}
+
+// GenerateToken - creates a new short-lived JWT access token carrying the user's role and session
+func GenerateToken(userID uint, role string, sessionID uint) (string, error) {
+	now := time.Now()
+	expirationTime := now.Add(AccessTokenTTL())
+
+	// jti lets a single token be revoked on logout
+	jti, err := GenerateOpaqueToken(16)
+	if err != nil {
+		return "", err
+	}
 
 	claims := &Claims{
-		UserID: userID,
+		UserID:    userID,
+		Role:      role,
+		SessionID: sessionID,
 		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			IssuedAt:  now.Unix(),
 			ExpiresAt: expirationTime.Unix(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return signClaims(claims)
 }
 
-// ValidateToken - verifies a JWT token
+// ValidateToken - verifies a JWT access token
 func ValidateToken(tokenString string) (*Claims, error) {
-	claims := &Claims{}
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+	// Purpose tokens (verification links etc.) must never work as access tokens
+	if claims.Purpose != "" {
+		return nil, errWrongTokenPurpose
+	}
 
-	if err != nil || !token.Valid {
+	return claims, nil
+}
+
+// GeneratePurposeToken - creates a signed token usable only for the given purpose, e.g. an email verification link
+func GeneratePurposeToken(userID uint, email, purpose string, ttl time.Duration) (string, error) {
+	now := time.Now()
+
+	// jti keeps two tokens issued in the same second distinct, so each can be tracked on its own
+	jti, err := GenerateOpaqueToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	claims := &Claims{
+		UserID:  userID,
+		Purpose: purpose,
+		Email:   email,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(ttl).Unix(),
+		},
+	}
+	return signClaims(claims)
+}
+
+// ValidatePurposeToken - verifies a token created by GeneratePurposeToken for the same purpose
+func ValidatePurposeToken(tokenString, purpose string) (*Claims, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != purpose {
+		return nil, errWrongTokenPurpose
+	}
+	return claims, nil
+}
+
+func signClaims(claims *Claims) (string, error) {
+	set, err := currentKeySet()
+	if err != nil {
+		return "", err
+	}
+
+	// kid tells verifiers (including other services reading our JWKS) which key to use
+	token := jwt.NewWithClaims(set.active.Method, claims)
+	token.Header["kid"] = set.active.ID
+	return token.SignedString(set.active.signKey)
+}
+
+func parseClaims(tokenString string) (*Claims, error) {
+	set, err := currentKeySet()
+	if err != nil {
 		return nil, err
 	}
 
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, set.lookupKey)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
 	return claims, nil
 }
 
+// GenerateOpaqueToken - returns a URL-safe random string with n bytes of entropy
+func GenerateOpaqueToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken - hashes an opaque token for storage; only the hash is ever persisted
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return fallback
+}
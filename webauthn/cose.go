@@ -0,0 +1,119 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// COSE algorithm identifiers accepted for credentials, in order of preference
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+// COSE key labels and values (RFC 9053)
+const (
+	coseKeyType   = 1
+	coseAlgorithm = 3
+	coseCurve     = -1
+	coseX         = -2
+	coseY         = -3
+	coseRSAN      = -1 // RSA keys reuse the negative labels for n and e
+	coseRSAE      = -2
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+)
+
+var (
+	errUnsupportedKey = errors.New("webauthn: unsupported credential public key")
+	errBadSignature   = errors.New("webauthn: signature verification failed")
+)
+
+// publicKey is a parsed COSE_Key
+type publicKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+// parsePublicKey decodes a COSE_Key as stored with a credential
+func parsePublicKey(coseKey []byte) (*publicKey, error) {
+	decoded, _, err := decodeCBOR(coseKey)
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, errUnsupportedKey
+	}
+	kty, _ := fields[int64(coseKeyType)].(int64)
+	alg, _ := fields[int64(coseAlgorithm)].(int64)
+	crv, _ := fields[int64(coseCurve)].(int64)
+	x, _ := fields[int64(coseX)].([]byte)
+	y, _ := fields[int64(coseY)].([]byte)
+
+	switch {
+	case alg == AlgES256 && kty == coseKeyTypeEC2 && crv == coseCurveP256:
+		if len(x) != 32 || len(y) != 32 {
+			return nil, errUnsupportedKey
+		}
+		// ecdh rejects points that are not on the curve
+		point := append(append([]byte{0x04}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, errUnsupportedKey
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		return &publicKey{alg: alg, key: key}, nil
+
+	case alg == AlgEdDSA && kty == coseKeyTypeOKP && crv == coseCurveEd25519:
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errUnsupportedKey
+		}
+		return &publicKey{alg: alg, key: ed25519.PublicKey(x)}, nil
+
+	case alg == AlgRS256 && kty == coseKeyTypeRSA:
+		n, _ := fields[int64(coseRSAN)].([]byte)
+		e, _ := fields[int64(coseRSAE)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, errUnsupportedKey
+		}
+		exponent := int(new(big.Int).SetBytes(e).Int64())
+		return &publicKey{alg: alg, key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}}, nil
+	}
+	return nil, errUnsupportedKey
+}
+
+// verify checks sig over data with the key's algorithm
+func (k *publicKey) verify(data, sig []byte) error {
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		if !ecdsa.VerifyASN1(key, digest[:], sig) {
+			return errBadSignature
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, data, sig) {
+			return errBadSignature
+		}
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) != nil {
+			return errBadSignature
+		}
+	default:
+		return errUnsupportedKey
+	}
+	return nil
+}
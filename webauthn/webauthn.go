@@ -0,0 +1,366 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Authenticator data flags
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+// How long the browser waits for the user, in milliseconds
+const ceremonyTimeout = 5 * 60 * 1000
+
+var (
+	ErrInvalidResponse = errors.New("webauthn: malformed authenticator response")
+	ErrChallenge       = errors.New("webauthn: challenge mismatch")
+	ErrOrigin          = errors.New("webauthn: origin not allowed")
+	ErrRelyingParty    = errors.New("webauthn: credential belongs to a different relying party")
+	ErrUserPresence    = errors.New("webauthn: user presence was not confirmed")
+	ErrSignCount       = errors.New("webauthn: signature counter went backwards; the authenticator may be cloned")
+)
+
+// RelyingParty is this service as the authenticator sees it
+type RelyingParty struct {
+	// ID is the domain credentials are scoped to, e.g. "gitconnect.dev"
+	ID   string
+	Name string
+	// Origins the browser may report, e.g. "https://gitconnect.dev"
+	Origins []string
+}
+
+// Default is the relying party used by the passkey endpoints
+var Default = NewRelyingPartyFromEnv()
+
+// NewRelyingPartyFromEnv reads WEBAUTHN_RP_ID, WEBAUTHN_RP_NAME and WEBAUTHN_ORIGINS
+// (comma-separated). The origin defaults to FRONTEND_URL and the ID to its host.
+func NewRelyingPartyFromEnv() *RelyingParty {
+	rp := &RelyingParty{
+		ID:   os.Getenv("WEBAUTHN_RP_ID"),
+		Name: os.Getenv("WEBAUTHN_RP_NAME"),
+	}
+	if rp.Name == "" {
+		rp.Name = "GitConnect"
+	}
+
+	origins := os.Getenv("WEBAUTHN_ORIGINS")
+	if origins == "" {
+		origins = os.Getenv("FRONTEND_URL")
+	}
+	for _, origin := range strings.Split(origins, ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			rp.Origins = append(rp.Origins, origin)
+		}
+	}
+	if len(rp.Origins) == 0 {
+		rp.Origins = []string{"http://localhost:3000"}
+	}
+
+	if rp.ID == "" {
+		if u, err := url.Parse(rp.Origins[0]); err == nil {
+			rp.ID = u.Hostname()
+		}
+	}
+	return rp
+}
+
+// NewChallenge returns 32 random bytes, encoded as they appear in options and client data
+func NewChallenge() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encode(b), nil
+}
+
+// CredentialDescriptor names a credential in allow and exclude lists
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+func descriptors(ids [][]byte) []CredentialDescriptor {
+	list := make([]CredentialDescriptor, len(ids))
+	for i, id := range ids {
+		list[i] = CredentialDescriptor{Type: "public-key", ID: encode(id)}
+	}
+	return list
+}
+
+// CreationOptions builds the options for navigator.credentials.create(). Binary fields are
+// base64url strings, as in PublicKeyCredential.parseCreationOptionsFromJSON().
+func (rp *RelyingParty) CreationOptions(challenge string, userHandle []byte, name, displayName string, exclude [][]byte) map[string]interface{} {
+	return map[string]interface{}{
+		"challenge": challenge,
+		"rp":        map[string]string{"id": rp.ID, "name": rp.Name},
+		"user": map[string]string{
+			"id":          encode(userHandle),
+			"name":        name,
+			"displayName": displayName,
+		},
+		"pubKeyCredParams": []map[string]interface{}{
+			{"type": "public-key", "alg": AlgES256},
+			{"type": "public-key", "alg": AlgEdDSA},
+			{"type": "public-key", "alg": AlgRS256},
+		},
+		"timeout":            ceremonyTimeout,
+		"excludeCredentials": descriptors(exclude),
+		"authenticatorSelection": map[string]string{
+			"residentKey":      "preferred",
+			"userVerification": "preferred",
+		},
+		"attestation": "none",
+	}
+}
+
+// RequestOptions builds the options for navigator.credentials.get(). An empty allow list lets
+// the user pick any passkey they hold for this site.
+func (rp *RelyingParty) RequestOptions(challenge string, allow [][]byte) map[string]interface{} {
+	return map[string]interface{}{
+		"challenge":        challenge,
+		"rpId":             rp.ID,
+		"timeout":          ceremonyTimeout,
+		"allowCredentials": descriptors(allow),
+		"userVerification": "preferred",
+	}
+}
+
+// RegistrationResponse is the JSON form of the credential returned by navigator.credentials.create()
+type RegistrationResponse struct {
+	ID       string `json:"id" binding:"required"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+		AttestationObject string `json:"attestationObject" binding:"required"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the JSON form of the credential returned by navigator.credentials.get()
+type AssertionResponse struct {
+	ID       string `json:"id" binding:"required"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+		AuthenticatorData string `json:"authenticatorData" binding:"required"`
+		Signature         string `json:"signature" binding:"required"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// Credential is what the server keeps after a successful registration
+type Credential struct {
+	ID        []byte
+	PublicKey []byte // COSE_Key
+	SignCount uint32
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// Challenge extracts the challenge from a response's clientDataJSON, so the caller can look up
+// the ceremony it belongs to before verifying the response
+func Challenge(clientDataJSON string) (string, error) {
+	raw, err := decode(clientDataJSON)
+	if err != nil {
+		return "", ErrInvalidResponse
+	}
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil || data.Challenge == "" {
+		return "", ErrInvalidResponse
+	}
+	return data.Challenge, nil
+}
+
+// VerifyRegistration checks a registration ceremony against the challenge that started it and
+// returns the new credential. Attestation is not requested, so attestation statements are not
+// verified: the credential is trusted because the logged-in user registered it.
+func (rp *RelyingParty) VerifyRegistration(challenge string, response *RegistrationResponse) (*Credential, error) {
+	if _, err := rp.verifyClientData(response.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	rawAttestation, err := decode(response.Response.AttestationObject)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	decoded, _, err := decodeCBOR(rawAttestation)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrInvalidResponse
+	}
+	authData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, ErrInvalidResponse
+	}
+
+	parsed, err := rp.verifyAuthenticatorData(authData)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.flags&flagAttested == 0 || len(parsed.credentialID) == 0 {
+		return nil, ErrInvalidResponse
+	}
+	if _, err := parsePublicKey(parsed.publicKey); err != nil {
+		return nil, err
+	}
+	if id, err := decode(response.ID); err != nil || !bytes.Equal(id, parsed.credentialID) {
+		return nil, ErrInvalidResponse
+	}
+
+	return &Credential{ID: parsed.credentialID, PublicKey: parsed.publicKey, SignCount: parsed.signCount}, nil
+}
+
+// Assertion is the outcome of a successful login ceremony
+type Assertion struct {
+	// SignCount is the authenticator's new signature counter, to be saved with the credential
+	SignCount uint32
+	// UserVerified is set when the authenticator checked a PIN or biometric, not just presence
+	UserVerified bool
+}
+
+// VerifyAssertion checks a login ceremony for a stored credential. A signature counter that does
+// not increase means the authenticator was cloned, unless both are zero (authenticators that do
+// not keep a counter, such as synced passkeys).
+func (rp *RelyingParty) VerifyAssertion(challenge string, response *AssertionResponse, credential *Credential) (*Assertion, error) {
+	rawClientData, err := rp.verifyClientData(response.Response.ClientDataJSON, "webauthn.get", challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	authData, err := decode(response.Response.AuthenticatorData)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	parsed, err := rp.verifyAuthenticatorData(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := decode(response.Response.Signature)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	key, err := parsePublicKey(credential.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	clientDataHash := sha256.Sum256(rawClientData)
+	if err := key.verify(append(append([]byte(nil), authData...), clientDataHash[:]...), signature); err != nil {
+		return nil, err
+	}
+
+	if (parsed.signCount != 0 || credential.SignCount != 0) && parsed.signCount <= credential.SignCount {
+		return nil, ErrSignCount
+	}
+	return &Assertion{SignCount: parsed.signCount, UserVerified: parsed.flags&flagUserVerified != 0}, nil
+}
+
+// verifyClientData checks the ceremony type, challenge and origin and returns the raw JSON,
+// whose hash the authenticator signed
+func (rp *RelyingParty) verifyClientData(clientDataJSON, ceremony, challenge string) ([]byte, error) {
+	raw, err := decode(clientDataJSON)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, ErrInvalidResponse
+	}
+	if data.Type != ceremony {
+		return nil, ErrInvalidResponse
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.TrimRight(data.Challenge, "=")), []byte(challenge)) != 1 {
+		return nil, ErrChallenge
+	}
+	for _, origin := range rp.Origins {
+		if data.Origin == origin {
+			return raw, nil
+		}
+	}
+	return nil, ErrOrigin
+}
+
+type authenticatorData struct {
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+// verifyAuthenticatorData parses authenticator data and checks it was made for this relying party
+// with the user present
+func (rp *RelyingParty) verifyAuthenticatorData(data []byte) (*authenticatorData, error) {
+	// rpIdHash (32) | flags (1) | signCount (4) | attested credential data (optional) | extensions
+	if len(data) < 37 {
+		return nil, ErrInvalidResponse
+	}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(data[:32], rpIDHash[:]) != 1 {
+		return nil, ErrRelyingParty
+	}
+
+	parsed := &authenticatorData{flags: data[32], signCount: binary.BigEndian.Uint32(data[33:37])}
+	if parsed.flags&flagUserPresent == 0 {
+		return nil, ErrUserPresence
+	}
+
+	if parsed.flags&flagAttested != 0 {
+		// aaguid (16) | credentialIdLength (2) | credentialId | credentialPublicKey (CBOR)
+		rest := data[37:]
+		if len(rest) < 18 {
+			return nil, ErrInvalidResponse
+		}
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < idLength {
+			return nil, ErrInvalidResponse
+		}
+		parsed.credentialID = append([]byte(nil), rest[:idLength]...)
+		rest = rest[idLength:]
+
+		_, keyLength, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, ErrInvalidResponse
+		}
+		parsed.publicKey = append([]byte(nil), rest[:keyLength]...)
+	}
+	return parsed, nil
+}
+
+// EncodeID encodes a credential ID the way browsers report it in a credential's id field
+func EncodeID(id []byte) string {
+	return encode(id)
+}
+
+// DecodeID reverses EncodeID
+func DecodeID(id string) ([]byte, error) {
+	return decode(id)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decode accepts base64url with or without padding, as browsers and libraries differ
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
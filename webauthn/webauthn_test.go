@@ -0,0 +1,229 @@
+package webauthn_test
+
+import (
+	"errors"
+	"testing"
+
+	"gitconnect-backend/webauthn"
+	"gitconnect-backend/webauthn/webauthntest"
+)
+
+const (
+	testRPID   = "gitconnect.test"
+	testOrigin = "https://gitconnect.test"
+)
+
+func testRelyingParty() *webauthn.RelyingParty {
+	return &webauthn.RelyingParty{ID: testRPID, Name: "GitConnect", Origins: []string{testOrigin}}
+}
+
+func newChallenge(t *testing.T) string {
+	t.Helper()
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return challenge
+}
+
+// register runs a registration ceremony that must succeed
+func register(t *testing.T, rp *webauthn.RelyingParty, authenticator *webauthntest.Authenticator) *webauthn.Credential {
+	t.Helper()
+	challenge := newChallenge(t)
+	credential, err := rp.VerifyRegistration(challenge, authenticator.Register(challenge))
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	return credential
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	rp := testRelyingParty()
+	authenticator := webauthntest.New(testRPID, testOrigin)
+
+	credential := register(t, rp, authenticator)
+	if string(credential.ID) != string(authenticator.CredentialID) {
+		t.Fatalf("credential ID = %x, want %x", credential.ID, authenticator.CredentialID)
+	}
+	if string(credential.PublicKey) != string(authenticator.COSEKey()) {
+		t.Fatal("stored public key differs from the authenticator's")
+	}
+
+	for i := 1; i <= 3; i++ {
+		challenge := newChallenge(t)
+		assertion, err := rp.VerifyAssertion(challenge, authenticator.Assert(challenge), credential)
+		if err != nil {
+			t.Fatalf("assertion %d: %v", i, err)
+		}
+		if assertion.SignCount != uint32(i) {
+			t.Fatalf("assertion %d: sign count = %d", i, assertion.SignCount)
+		}
+		if assertion.UserVerified {
+			t.Fatalf("assertion %d: reported user verification the authenticator did not do", i)
+		}
+		credential.SignCount = assertion.SignCount
+	}
+
+	authenticator.UserVerified = true
+	challenge := newChallenge(t)
+	assertion, err := rp.VerifyAssertion(challenge, authenticator.Assert(challenge), credential)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assertion.UserVerified {
+		t.Fatal("user verification was not reported")
+	}
+}
+
+func TestChallengeExtraction(t *testing.T) {
+	authenticator := webauthntest.New(testRPID, testOrigin)
+	challenge := newChallenge(t)
+	got, err := webauthn.Challenge(authenticator.Assert(challenge).Response.ClientDataJSON)
+	if err != nil || got != challenge {
+		t.Fatalf("Challenge = %q, %v; want %q", got, err, challenge)
+	}
+}
+
+func TestBadChallenge(t *testing.T) {
+	rp := testRelyingParty()
+	authenticator := webauthntest.New(testRPID, testOrigin)
+
+	response := authenticator.Register(newChallenge(t))
+	if _, err := rp.VerifyRegistration(newChallenge(t), response); !errors.Is(err, webauthn.ErrChallenge) {
+		t.Fatalf("registration with another challenge: err = %v, want ErrChallenge", err)
+	}
+
+	credential := register(t, rp, authenticator)
+	assertion := authenticator.Assert(newChallenge(t))
+	if _, err := rp.VerifyAssertion(newChallenge(t), assertion, credential); !errors.Is(err, webauthn.ErrChallenge) {
+		t.Fatalf("assertion with another challenge: err = %v, want ErrChallenge", err)
+	}
+}
+
+func TestCeremonyTypeMismatch(t *testing.T) {
+	rp := testRelyingParty()
+	authenticator := webauthntest.New(testRPID, testOrigin)
+	credential := register(t, rp, authenticator)
+
+	// A registration's client data must not pass as an assertion's
+	challenge := newChallenge(t)
+	response := authenticator.Assert(challenge)
+	response.Response.ClientDataJSON = authenticator.Register(challenge).Response.ClientDataJSON
+	if _, err := rp.VerifyAssertion(challenge, response, credential); !errors.Is(err, webauthn.ErrInvalidResponse) {
+		t.Fatalf("err = %v, want ErrInvalidResponse", err)
+	}
+}
+
+func TestWrongOrigin(t *testing.T) {
+	rp := testRelyingParty()
+	authenticator := webauthntest.New(testRPID, testOrigin)
+	credential := register(t, rp, authenticator)
+
+	for _, origin := range []string{"https://evil.test", "http://gitconnect.test", "https://gitconnect.test.evil.test"} {
+		authenticator.Origin = origin
+		challenge := newChallenge(t)
+		if _, err := rp.VerifyRegistration(challenge, authenticator.Register(challenge)); !errors.Is(err, webauthn.ErrOrigin) {
+			t.Errorf("registration from %s: err = %v, want ErrOrigin", origin, err)
+		}
+		challenge = newChallenge(t)
+		if _, err := rp.VerifyAssertion(challenge, authenticator.Assert(challenge), credential); !errors.Is(err, webauthn.ErrOrigin) {
+			t.Errorf("assertion from %s: err = %v, want ErrOrigin", origin, err)
+		}
+	}
+}
+
+func TestWrongRelyingParty(t *testing.T) {
+	rp := testRelyingParty()
+	authenticator := webauthntest.New("evil.test", testOrigin)
+	challenge := newChallenge(t)
+	if _, err := rp.VerifyRegistration(challenge, authenticator.Register(challenge)); !errors.Is(err, webauthn.ErrRelyingParty) {
+		t.Fatalf("err = %v, want ErrRelyingParty", err)
+	}
+}
+
+func TestSignCountRegression(t *testing.T) {
+	rp := testRelyingParty()
+	authenticator := webauthntest.New(testRPID, testOrigin)
+	credential := register(t, rp, authenticator)
+	credential.SignCount = 10
+
+	// A clone left behind at a lower count, and one that replays the stored count
+	for _, count := range []uint32{4, 9} {
+		authenticator.SignCount = count
+		challenge := newChallenge(t)
+		if _, err := rp.VerifyAssertion(challenge, authenticator.Assert(challenge), credential); !errors.Is(err, webauthn.ErrSignCount) {
+			t.Fatalf("count %d after 10: err = %v, want ErrSignCount", count+1, err)
+		}
+	}
+
+	authenticator.SignCount = 10
+	challenge := newChallenge(t)
+	assertion, err := rp.VerifyAssertion(challenge, authenticator.Assert(challenge), credential)
+	if err != nil || assertion.SignCount != 11 {
+		t.Fatalf("count 11 after 10: %+v, %v", assertion, err)
+	}
+}
+
+func TestZeroSignCountAllowed(t *testing.T) {
+	rp := testRelyingParty()
+	authenticator := webauthntest.New(testRPID, testOrigin)
+	credential := register(t, rp, authenticator)
+
+	// Synced passkeys keep no counter and always report zero
+	for i := 0; i < 2; i++ {
+		authenticator.SignCount = ^uint32(0) // Assert increments it to 0
+		challenge := newChallenge(t)
+		if _, err := rp.VerifyAssertion(challenge, authenticator.Assert(challenge), credential); err != nil {
+			t.Fatalf("assertion %d: %v", i, err)
+		}
+	}
+}
+
+func TestTwoAuthenticatorsOneUser(t *testing.T) {
+	rp := testRelyingParty()
+	laptop := webauthntest.New(testRPID, testOrigin)
+	phone := webauthntest.New(testRPID, testOrigin)
+	laptopCredential := register(t, rp, laptop)
+	phoneCredential := register(t, rp, phone)
+	if string(laptopCredential.ID) == string(phoneCredential.ID) {
+		t.Fatal("both authenticators produced the same credential ID")
+	}
+
+	// Each signs with its own key and keeps its own counter
+	for i := 0; i < 3; i++ {
+		challenge := newChallenge(t)
+		assertion, err := rp.VerifyAssertion(challenge, laptop.Assert(challenge), laptopCredential)
+		if err != nil {
+			t.Fatalf("laptop: %v", err)
+		}
+		laptopCredential.SignCount = assertion.SignCount
+	}
+	challenge := newChallenge(t)
+	assertion, err := rp.VerifyAssertion(challenge, phone.Assert(challenge), phoneCredential)
+	if err != nil {
+		t.Fatalf("phone after the laptop was used: %v", err)
+	}
+	if assertion.SignCount != 1 {
+		t.Fatalf("phone sign count = %d, want 1", assertion.SignCount)
+	}
+
+	// One authenticator's signature does not verify against the other's credential
+	challenge = newChallenge(t)
+	if _, err := rp.VerifyAssertion(challenge, phone.Assert(challenge), laptopCredential); err == nil {
+		t.Fatal("phone assertion verified against the laptop credential")
+	}
+}
+
+func TestTamperedSignature(t *testing.T) {
+	rp := testRelyingParty()
+	authenticator := webauthntest.New(testRPID, testOrigin)
+	credential := register(t, rp, authenticator)
+
+	// Signed for one challenge, then the client data swapped for another ceremony's
+	challenge := newChallenge(t)
+	response := authenticator.Assert(newChallenge(t))
+	response.Response.ClientDataJSON = authenticator.Assert(challenge).Response.ClientDataJSON
+	if _, err := rp.VerifyAssertion(challenge, response, credential); err == nil {
+		t.Fatal("assertion with swapped client data verified")
+	}
+}
@@ -0,0 +1,185 @@
+// Package webauthntest provides a software authenticator for testing passkey ceremonies without
+// a browser or security key.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+
+	"gitconnect-backend/webauthn"
+)
+
+// Authenticator flags, as in authenticator data
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+// Authenticator holds one ES256 credential and answers create() and get() as a browser would
+// pass them on. Fields can be changed between ceremonies to produce bad responses.
+type Authenticator struct {
+	RPID         string // Relying party the credential is scoped to
+	Origin       string // Origin reported in client data
+	CredentialID []byte
+	Key          *ecdsa.PrivateKey
+	SignCount    uint32 // Incremented before each assertion
+	UserVerified bool   // Report that a PIN or biometric was checked
+}
+
+// New returns an authenticator with a fresh key and credential ID for rpID, used from origin
+func New(rpID, origin string) *Authenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return &Authenticator{RPID: rpID, Origin: origin, CredentialID: id, Key: key}
+}
+
+// ID is the credential ID as reported in the credential's id field
+func (a *Authenticator) ID() string {
+	return webauthn.EncodeID(a.CredentialID)
+}
+
+// Register answers navigator.credentials.create() for challenge
+func (a *Authenticator) Register(challenge string) *webauthn.RegistrationResponse {
+	authData := a.authenticatorData(flagAttested)
+	authData = append(authData, make([]byte, 16)...) // aaguid
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.CredentialID)))
+	authData = append(authData, a.CredentialID...)
+	authData = append(authData, a.COSEKey()...)
+
+	attestation := cborMap(map[interface{}][]byte{
+		"fmt":      cborText("none"),
+		"attStmt":  cborMap(nil),
+		"authData": cborBytes(authData),
+	})
+
+	response := &webauthn.RegistrationResponse{ID: a.ID(), Type: "public-key"}
+	response.Response.ClientDataJSON = a.clientData("webauthn.create", challenge)
+	response.Response.AttestationObject = encode(attestation)
+	return response
+}
+
+// Assert answers navigator.credentials.get() for challenge, counting one more signature
+func (a *Authenticator) Assert(challenge string) *webauthn.AssertionResponse {
+	a.SignCount++
+	authData := a.authenticatorData(0)
+	clientDataJSON := a.clientData("webauthn.get", challenge)
+	rawClientData, _ := base64.RawURLEncoding.DecodeString(clientDataJSON)
+	clientDataHash := sha256.Sum256(rawClientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.Key, digest[:])
+	if err != nil {
+		panic(err)
+	}
+
+	response := &webauthn.AssertionResponse{ID: a.ID(), Type: "public-key"}
+	response.Response.ClientDataJSON = clientDataJSON
+	response.Response.AuthenticatorData = encode(authData)
+	response.Response.Signature = encode(signature)
+	return response
+}
+
+// COSEKey is the credential's public key as the server stores it
+func (a *Authenticator) COSEKey() []byte {
+	point := a.Key.PublicKey
+	x, y := make([]byte, 32), make([]byte, 32)
+	point.X.FillBytes(x)
+	point.Y.FillBytes(y)
+	return cborMap(map[interface{}][]byte{
+		int64(1):  cborInt(2),                 // kty: EC2
+		int64(3):  cborInt(webauthn.AlgES256), // alg
+		int64(-1): cborInt(1),                 // crv: P-256
+		int64(-2): cborBytes(x),
+		int64(-3): cborBytes(y),
+	})
+}
+
+func (a *Authenticator) authenticatorData(extraFlags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	flags := byte(flagUserPresent) | extraFlags
+	if a.UserVerified {
+		flags |= flagUserVerified
+	}
+	data := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, a.SignCount)
+}
+
+func (a *Authenticator) clientData(ceremony, challenge string) string {
+	raw, _ := json.Marshal(map[string]interface{}{
+		"type":        ceremony,
+		"challenge":   challenge,
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+	return encode(raw)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Just enough CBOR to build attestation objects and COSE keys
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{major<<5 | 24, byte(n)}
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	}
+}
+
+func cborInt(n int64) []byte {
+	if n < 0 {
+		return cborHead(1, uint64(-1-n))
+	}
+	return cborHead(0, uint64(n))
+}
+
+func cborBytes(b []byte) []byte {
+	return append(cborHead(2, uint64(len(b))), b...)
+}
+
+func cborText(s string) []byte {
+	return append(cborHead(3, uint64(len(s))), s...)
+}
+
+// cborMap encodes keys (int64 or string) in a fixed order so output is deterministic
+func cborMap(entries map[interface{}][]byte) []byte {
+	type entry struct {
+		key   []byte
+		value []byte
+	}
+	list := make([]entry, 0, len(entries))
+	for k, v := range entries {
+		switch key := k.(type) {
+		case int64:
+			list = append(list, entry{cborInt(key), v})
+		case string:
+			list = append(list, entry{cborText(key), v})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return string(list[i].key) < string(list[j].key) })
+
+	out := cborHead(5, uint64(len(list)))
+	for _, e := range list {
+		out = append(append(out, e.key...), e.value...)
+	}
+	return out
+}